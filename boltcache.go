@@ -0,0 +1,244 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	dataBucket = "data"
+	metaBucket = "meta"
+)
+
+const boltTimeout = time.Second
+
+// BoltCacheOptions configures a bolt backed cache. bolt holds an exclusive
+// lock on the file while it is open, so a cache file can be shared by
+// several processes only one after the other: opening it while another
+// process has it open fails once Timeout elapses.
+type BoltCacheOptions struct {
+	Path    string
+	Mode    os.FileMode
+	Keep    bool
+	Bucket  string
+	Timeout time.Duration
+}
+
+type boltcache struct {
+	db   *bolt.DB
+	file string
+	keep bool
+	ns   []byte
+}
+
+func BoltCache(ttl time.Duration, options ...CacheOption) (Cache, error) {
+	return OpenBoltCache(BoltCacheOptions{}, ttl, options...)
+}
+
+func OpenBoltCache(opts BoltCacheOptions, ttl time.Duration, options ...CacheOption) (Cache, error) {
+	if opts.Path == "" {
+		opts.Path = cacheFile
+	}
+	if opts.Mode == 0 {
+		opts.Mode = 0644
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = boltTimeout
+	}
+	if !opts.Keep {
+		os.Remove(opts.Path)
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(opts.Path, opts.Mode, &bolt.Options{Timeout: opts.Timeout})
+	if err != nil {
+		return nil, err
+	}
+	c := boltcache{
+		db:   db,
+		file: opts.Path,
+		keep: opts.Keep,
+	}
+	if opts.Bucket != "" {
+		c.ns = []byte(opts.Bucket)
+	}
+	err = c.db.Update(func(tx *bolt.Tx) error {
+		create := tx.CreateBucketIfNotExists
+		if c.ns != nil {
+			bk, err := tx.CreateBucketIfNotExists(c.ns)
+			if err != nil {
+				return err
+			}
+			create = bk.CreateBucketIfNotExists
+		}
+		_, err := create([]byte(dataBucket))
+		if err == nil {
+			_, err = create([]byte(metaBucket))
+		}
+		return err
+	})
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	bc := newCache(&c, ttl, options)
+	if bc.err != nil {
+		bc.Close()
+		return nil, bc.err
+	}
+	return bc, nil
+}
+
+func (b *boltcache) Close() error {
+	if err := b.db.Close(); err != nil || b.keep {
+		return err
+	}
+	return os.Remove(b.file)
+}
+
+func (b *boltcache) bucket(tx *bolt.Tx, name string) *bolt.Bucket {
+	if b.ns == nil {
+		return tx.Bucket([]byte(name))
+	}
+	return tx.Bucket(b.ns).Bucket([]byte(name))
+}
+
+func (b *boltcache) lookup(url string) (*meta, error) {
+	var (
+		key = b.key(url)
+		m   meta
+	)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bk := b.bucket(tx, metaBucket)
+		vs := bk.Get(key)
+		if vs == nil {
+			return errMissing
+		}
+		return json.Unmarshal(vs, &m)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (b *boltcache) open(url string) (io.ReadCloser, error) {
+	var (
+		key = b.key(url)
+		buf []byte
+	)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bk := b.bucket(tx, dataBucket)
+		vs := bk.Get(key)
+		if vs == nil {
+			return errMissing
+		}
+		buf = append(buf, vs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return byteReader{bytes.NewReader(buf)}, nil
+}
+
+func (b *boltcache) create(url string, m *meta) (writer, error) {
+	w := boltwriter{
+		cache: b,
+		key:   b.key(url),
+		meta:  m,
+	}
+	return &w, nil
+}
+
+func (b *boltcache) update(url string, m *meta) error {
+	ms, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	key := b.key(url)
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bk := b.bucket(tx, metaBucket)
+		if bk.Get(key) == nil {
+			return errMissing
+		}
+		return bk.Put(key, ms)
+	})
+}
+
+func (b *boltcache) remove(url string) error {
+	key := b.key(url)
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if err := b.bucket(tx, metaBucket).Delete(key); err != nil {
+			return err
+		}
+		return b.bucket(tx, dataBucket).Delete(key)
+	})
+}
+
+func (b *boltcache) walk(fn func(*meta) error) error {
+	var ms []meta
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return b.bucket(tx, metaBucket).ForEach(func(_, vs []byte) error {
+			var m meta
+			if err := json.Unmarshal(vs, &m); err != nil {
+				return nil
+			}
+			ms = append(ms, m)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	for i := range ms {
+		if err := fn(&ms[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *boltcache) key(str string) []byte {
+	return []byte(str)
+}
+
+type byteReader struct {
+	*bytes.Reader
+}
+
+func (byteReader) Close() error {
+	return nil
+}
+
+type boltwriter struct {
+	bytes.Buffer
+	cache *boltcache
+	key   []byte
+	meta  *meta
+}
+
+func (w *boltwriter) commit() error {
+	ms, err := json.Marshal(w.meta)
+	if err != nil {
+		return err
+	}
+	return w.cache.db.Update(func(tx *bolt.Tx) error {
+		if err := w.cache.bucket(tx, metaBucket).Put(w.key, ms); err != nil {
+			return err
+		}
+		return w.cache.bucket(tx, dataBucket).Put(w.key, w.Bytes())
+	})
+}
+
+func (w *boltwriter) abort() error {
+	w.Reset()
+	return nil
+}
@@ -1,25 +1,54 @@
 package fetch
 
 import (
-	"bytes"
-	"encoding/binary"
+	"compress/flate"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"hash/adler32"
+	"hash"
+	"hash/crc32"
 	"io"
+	"net/http"
 	urllib "net/url"
-	"os"
-	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/midbel/xxh"
-	bolt "go.etcd.io/bbolt"
 )
 
 type Cache interface {
-	Get(string, DoFunc) error
-	Do(*urllib.URL, DoFunc) DoFunc
+	Get(*http.Request, DoFunc) error
+	Stale(*http.Request, DoFunc) error
+	Do(*http.Request, *http.Response, DoFunc) DoFunc
+	Invalidate(*http.Request, *http.Response)
+
+	Delete(string) error
+	Purge() error
+	Stats() CacheStats
+	Walk(func(CacheEntry) error) error
+	Close() error
+}
+
+type CacheStats struct {
+	Hits      int64
+	Misses    int64
+	Stale     int64
+	Evictions int64
+	Entries   int
+	Bytes     int64
+}
+
+type CacheEntry struct {
+	URL      string
+	Type     string
+	Size     int64
+	Stored   time.Time
+	Age      time.Duration
+	Lifetime time.Duration
 }
 
 const (
@@ -28,270 +57,1046 @@ const (
 )
 
 var (
-	errMissing = errors.New("missing")
-	errExpired = errors.New("expired")
+	errMissing    = errors.New("missing")
+	errExpired    = errors.New("expired")
+	errRevalidate = errors.New("revalidate")
+	errCorrupted  = errors.New("corrupted")
+	errCodec      = errors.New("unsupported codec")
 )
 
-type filecache struct {
-	dir string
-	ttl time.Duration
+type CacheMode int
+
+const (
+	ModeTTL CacheMode = iota
+	ModePrivate
+	ModeShared
+)
+
+type KeyFunc func(*http.Request) string
+
+func DefaultKey(req *http.Request) string {
+	return req.Method + " " + req.URL.String()
+}
+
+func HeaderKey(names ...string) KeyFunc {
+	return func(req *http.Request) string {
+		var str strings.Builder
+		str.WriteString(DefaultKey(req))
+		for _, n := range names {
+			str.WriteString("\n")
+			str.WriteString(http.CanonicalHeaderKey(n))
+			str.WriteString(": ")
+			str.WriteString(strings.Join(req.Header.Values(n), ", "))
+		}
+		return str.String()
+	}
+}
+
+func CredentialKey(req *http.Request) string {
+	return HeaderKey("authorization", "cookie")(req)
+}
+
+type CacheOption func(*cache)
+
+func WithKeyFunc(fn KeyFunc) CacheOption {
+	return func(c *cache) {
+		if fn != nil {
+			c.keyfn = fn
+		}
+	}
+}
+
+func WithCacheMode(mode CacheMode) CacheOption {
+	return func(c *cache) {
+		c.mode = mode
+	}
+}
+
+func WithMaxBytes(n int64) CacheOption {
+	return func(c *cache) {
+		c.maxBytes = n
+	}
+}
 
-	mu    sync.Mutex
-	items map[uint32]*item
-	size  int
+func WithMaxEntrySize(n int64) CacheOption {
+	return func(c *cache) {
+		c.maxEntry = n
+	}
 }
 
-func FileCache(dir string, size int, ttl time.Duration) Cache {
-	c := filecache{
-		dir:   filepath.Join(dir, cacheFile),
-		ttl:   ttl,
-		size:  size,
-		items: make(map[uint32]*item),
+func WithMaxEntries(n int) CacheOption {
+	return func(c *cache) {
+		c.maxItems = n
 	}
-	os.RemoveAll(c.dir)
-	if ttl != 0 {
-		go c.clean(ttl * 5)
+}
+
+func WithCompression(codec string) CacheOption {
+	return func(c *cache) {
+		switch codec {
+		case "", encgzip, encflate:
+			c.codec = codec
+		default:
+			c.err = fmt.Errorf("%s: %w", codec, errCodec)
+		}
+	}
+}
+
+func WithStaleWhileRevalidate(limit time.Duration) CacheOption {
+	return func(c *cache) {
+		c.revalidateLimit = limit
+	}
+}
+
+func WithStaleIfError(limit time.Duration) CacheOption {
+	return func(c *cache) {
+		c.errorLimit = limit
+	}
+}
+
+type store interface {
+	lookup(string) (*meta, error)
+	open(string) (io.ReadCloser, error)
+	create(string, *meta) (writer, error)
+	update(string, *meta) error
+	remove(string) error
+	walk(func(*meta) error) error
+}
+
+type writer interface {
+	io.Writer
+	commit() error
+	abort() error
+}
+
+type cache struct {
+	hits      int64
+	misses    int64
+	stale     int64
+	evictions int64
+
+	store
+	ttl   time.Duration
+	mode  CacheMode
+	keyfn KeyFunc
+	codec string
+	err   error
+
+	revalidateLimit time.Duration
+	errorLimit      time.Duration
+
+	maxBytes int64
+	maxEntry int64
+	maxItems int
+	lru      *lru
+
+	mu      sync.Mutex
+	pending map[string]time.Time
+	marking sync.Mutex
+}
+
+func newCache(s store, ttl time.Duration, options []CacheOption) *cache {
+	c := cache{
+		store:           s,
+		ttl:             ttl,
+		keyfn:           DefaultKey,
+		revalidateLimit: -1,
+		errorLimit:      -1,
+		pending:         make(map[string]time.Time),
+	}
+	for _, fn := range options {
+		fn(&c)
+	}
+	c.lru = newLRU(c.maxBytes, c.maxItems)
+	for _, k := range c.lru.load(s) {
+		c.evict(k)
 	}
 	return &c
 }
 
-func (c *filecache) Get(url string, do DoFunc) error {
-	if c.ttl <= 0 {
-		return errExpired
+func cacheError(c Cache) error {
+	if x, ok := c.(*cache); ok {
+		return x.err
 	}
-	i, err := c.get(url)
-	if err == nil {
-		err = i.get(do)
+	return nil
+}
+
+func (c *cache) Close() error {
+	if x, ok := c.store.(io.Closer); ok {
+		return x.Close()
+	}
+	return nil
+}
+
+func (c *cache) Get(req *http.Request, do DoFunc) error {
+	if c.err != nil {
+		return c.err
+	}
+	err := c.get(req, do)
+	if err == errMissing || err == errExpired {
+		atomic.AddInt64(&c.misses, 1)
 	}
 	return err
 }
 
-func (c *filecache) Do(loc *urllib.URL, do DoFunc) DoFunc {
-	if c.ttl <= 0 {
+func (c *cache) get(req *http.Request, do DoFunc) error {
+	if c.mode == ModeTTL && c.ttl <= 0 {
+		return errMissing
+	}
+	if c.mode != ModeTTL && requestControl(req).has("no-store") {
+		return errMissing
+	}
+	key, m, err := c.find(req)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if c.isFresh(m, req, now) {
+		if err := c.serve(req, key, m, do); err != nil {
+			return err
+		}
+		atomic.AddInt64(&c.hits, 1)
+		return nil
+	}
+	cond := m.condition(req)
+	if c.canServeStale(m, req, "stale-while-revalidate", c.revalidateLimit, now) {
+		if err := c.serve(req, key, m, do); err != nil {
+			return err
+		}
+		atomic.AddInt64(&c.stale, 1)
+		if c.markPending(c.key(req), now) {
+			return errRevalidate
+		}
+		return nil
+	}
+	if !cond && c.mode == ModeTTL && c.revalidateLimit < 0 && c.errorLimit < 0 {
+		c.drop(key)
+	}
+	return errExpired
+}
+
+func (c *cache) Stale(req *http.Request, do DoFunc) error {
+	if c.err != nil {
+		return c.err
+	}
+	key, m, err := c.find(req)
+	if err != nil {
+		return err
+	}
+	if !c.canServeStale(m, req, "stale-if-error", c.errorLimit, time.Now()) {
+		return errExpired
+	}
+	if err := c.serve(req, key, m, do); err != nil {
+		return err
+	}
+	atomic.AddInt64(&c.stale, 1)
+	return nil
+}
+
+func (c *cache) serve(req *http.Request, key string, m *meta, do DoFunc) error {
+	r, err := c.open(key)
+	if err != nil {
+		return errMissing
+	}
+	defer r.Close()
+	if err := m.verify(r); err != nil {
+		c.drop(key)
+		return errMissing
+	}
+	rs, err := decodeWith(m.Codec, r)
+	if err != nil {
+		c.drop(key)
+		return errMissing
+	}
+	c.lru.touch(key)
+	if m.Parent != "" {
+		c.lru.touch(m.Parent)
+	}
+	if res := captured(req); res != nil {
+		res.cached(m)
+	}
+	return do(m.Type, rs)
+}
+
+func (c *cache) Do(req *http.Request, res *http.Response, do DoFunc) DoFunc {
+	if c.err != nil {
+		return do
+	}
+	c.clearPending(c.key(req))
+	if res.StatusCode == http.StatusNotModified {
+		return c.revalidate(req, res, do)
+	}
+	if !c.isStorable(req, res) {
+		return do
+	}
+	if c.maxEntry > 0 && res.ContentLength > c.maxEntry {
 		return do
 	}
-	key := c.key(loc.String())
+	m := c.makeMeta(req, res, time.Now())
+	key, err := c.entryKey(req, m)
+	if err != nil {
+		return do
+	}
+	m.Key, m.Codec = key, c.codec
 	return func(ct string, r io.Reader) error {
-		c.mu.Lock()
-		defer c.mu.Unlock()
-
-		file, err := c.prepare(loc.String(), loc.Hostname())
+		m.Type = ct
+		w, err := c.create(key, m)
 		if err != nil {
-			return err
+			return do(ct, r)
 		}
-		w, err := os.Create(file)
+		sum := makeDigest()
+		enc, err := encodeWith(m.Codec, io.MultiWriter(w, sum))
 		if err != nil {
+			w.abort()
+			return do(ct, r)
+		}
+		tee := limitWriter{
+			Writer: enc,
+			limit:  c.maxEntry,
+		}
+		if err := do(ct, io.TeeReader(r, &tee)); err != nil {
+			w.abort()
 			return err
 		}
-		defer w.Close()
+		err = tee.drain(r)
+		if err == nil {
+			err = enc.Close()
+		}
+		if err != nil || tee.failed() {
+			w.abort()
+			return nil
+		}
+		m.Size, m.Sum = sum.size, sum.Sum32()
+		if err := w.commit(); err == nil {
+			c.admit(key, m.Size)
+		}
+		return nil
+	}
+}
 
-		if err = do(ct, io.TeeReader(r, w)); err == nil {
-			c.items[key] = makeItem(file, ct)
+func (c *cache) fill(req *http.Request, m *meta, body []byte) error {
+	if c.maxEntry > 0 && int64(len(body)) > c.maxEntry {
+		return nil
+	}
+	x := *m
+	key, err := c.entryKey(req, &x)
+	if err != nil {
+		return err
+	}
+	x.Key, x.Codec = key, c.codec
+	w, err := c.create(key, &x)
+	if err != nil {
+		return err
+	}
+	sum := makeDigest()
+	enc, err := encodeWith(x.Codec, io.MultiWriter(w, sum))
+	if err == nil {
+		if _, err = enc.Write(body); err == nil {
+			err = enc.Close()
 		}
+	}
+	if err != nil {
+		w.abort()
 		return err
 	}
+	x.Size, x.Sum = sum.size, sum.Sum32()
+	if err := w.commit(); err != nil {
+		return err
+	}
+	c.admit(key, x.Size)
+	return nil
 }
 
-func (c *filecache) prepare(url, dir string) (string, error) {
-	file := fmt.Sprintf("%16x", xxh.Sum64([]byte(url), 0))
-	file = filepath.Join(c.dir, dir, file)
-	return file, os.MkdirAll(filepath.Dir(file), 0755)
+func (c *cache) entryKey(req *http.Request, m *meta) (string, error) {
+	key := c.key(req)
+	m.Parent = ""
+	if len(m.Vary) == 0 {
+		return key, nil
+	}
+	names := make([]string, 0, len(m.Vary))
+	for k := range m.Vary {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	variant := c.variant(req, names)
+	if err := c.mark(key, variant, m, names); err != nil {
+		return "", err
+	}
+	m.Parent = key
+	return variant, nil
 }
 
-func (c *filecache) get(url string) (*item, error) {
-	key := c.key(url)
+func (c *cache) admit(key string, size int64) {
+	for _, k := range c.lru.add(key, size) {
+		c.evict(k)
+	}
+}
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (c *cache) Invalidate(req *http.Request, res *http.Response) {
+	if isSafe(req.Method) || res.StatusCode < 200 || res.StatusCode >= 400 {
+		return
+	}
+	urls := []*urllib.URL{req.URL}
+	for _, h := range []string{"location", "content-location"} {
+		str := res.Header.Get(h)
+		if str == "" {
+			continue
+		}
+		u, err := req.URL.Parse(str)
+		if err != nil || u.Scheme != req.URL.Scheme || u.Host != req.URL.Host {
+			continue
+		}
+		urls = append(urls, u)
+	}
+	for _, u := range urls {
+		r := req.Clone(req.Context())
+		r.Method, r.URL = http.MethodGet, u
+		r.Body, r.GetBody, r.ContentLength = nil, nil, 0
+		c.invalidate(r)
+	}
+}
 
-	i, ok := c.items[key]
-	if !ok {
-		return nil, errMissing
+func (c *cache) invalidate(req *http.Request) {
+	key := c.key(req)
+	m, err := c.lookup(key)
+	if err != nil {
+		return
+	}
+	for _, k := range m.Keys {
+		c.drop(k)
+	}
+	if len(m.Variants) > 0 {
+		c.drop(c.variant(req, m.Variants))
+	}
+	c.drop(key)
+}
+
+func (c *cache) Delete(url string) error {
+	if u, err := urllib.Parse(url); err == nil {
+		url = u.String()
+	}
+	return c.dropWhere(func(m *meta) bool {
+		return m.URL == url
+	})
+}
+
+func (c *cache) Purge() error {
+	return c.dropWhere(func(_ *meta) bool {
+		return true
+	})
+}
+
+func (c *cache) Stats() CacheStats {
+	entries, bytes := c.lru.stats()
+	return CacheStats{
+		Hits:      atomic.LoadInt64(&c.hits),
+		Misses:    atomic.LoadInt64(&c.misses),
+		Stale:     atomic.LoadInt64(&c.stale),
+		Evictions: atomic.LoadInt64(&c.evictions),
+		Entries:   entries,
+		Bytes:     bytes,
+	}
+}
+
+func (c *cache) Walk(fn func(CacheEntry) error) error {
+	now := time.Now()
+	return c.walk(func(m *meta) error {
+		if len(m.Variants) > 0 {
+			return nil
+		}
+		e := CacheEntry{
+			URL:      m.URL,
+			Type:     m.Type,
+			Size:     m.Size,
+			Stored:   m.Stored,
+			Age:      m.age(now),
+			Lifetime: m.Lifetime,
+		}
+		return fn(e)
+	})
+}
+
+func (c *cache) dropWhere(fn func(*meta) bool) error {
+	var keys []string
+	err := c.walk(func(m *meta) error {
+		if fn(m) {
+			keys = append(keys, m.Key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := c.drop(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *cache) find(req *http.Request) (string, *meta, error) {
+	key := c.key(req)
+	m, err := c.lookup(key)
+	if err == nil && len(m.Variants) > 0 {
+		key = c.variant(req, m.Variants)
+		m, err = c.lookup(key)
+	}
+	if err != nil {
+		return key, nil, err
 	}
-	if i.isExpired(c.ttl) {
-		delete(c.items, key)
-		return nil, errExpired
+	if m.URL != req.URL.String() {
+		return key, nil, errMissing
 	}
-	return i, nil
+	if c.mode != ModeTTL && !m.matches(req) {
+		return key, nil, errMissing
+	}
+	return key, m, nil
 }
 
-func (c *filecache) clean(wait time.Duration) {
-	if c.size <= 0 {
+func (c *cache) mark(key, variant string, m *meta, names []string) error {
+	c.marking.Lock()
+	x := meta{
+		Key:      key,
+		URL:      m.URL,
+		Stored:   m.Stored,
+		Variants: names,
+		Keys:     []string{variant},
+	}
+	if p, err := c.lookup(key); err == nil {
+		for _, k := range p.Keys {
+			if k != variant {
+				x.Keys = append(x.Keys, k)
+			}
+		}
+	}
+	w, err := c.create(key, &x)
+	if err == nil {
+		err = w.commit()
+	}
+	c.marking.Unlock()
+	if err == nil {
+		c.admit(key, x.weight())
+	}
+	return err
+}
+
+func (c *cache) unmark(key, variant string) {
+	c.marking.Lock()
+	defer c.marking.Unlock()
+
+	m, err := c.lookup(key)
+	if err != nil || len(m.Variants) == 0 {
+		return
+	}
+	var keys []string
+	for _, k := range m.Keys {
+		if k != variant {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		c.drop(key)
 		return
 	}
-	for n := range time.Tick(wait) {
-		if len(c.items) < c.size {
+	m.Keys = keys
+	c.update(key, m)
+}
+
+func (c *cache) orphaned(m *meta) bool {
+	for _, k := range m.Keys {
+		if _, err := c.lookup(k); err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *cache) sweep(every time.Duration, done <-chan struct{}) {
+	tick := time.NewTicker(every)
+	defer tick.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-tick.C:
+			c.dropWhere(func(m *meta) bool {
+				if len(m.Variants) > 0 {
+					return now.Sub(m.Stored) > every && c.orphaned(m)
+				}
+				return !m.revalidable() && m.age(now) > m.Lifetime+c.grace(m)
+			})
+		}
+	}
+}
+
+func (c *cache) grace(m *meta) time.Duration {
+	var (
+		grace time.Duration
+		cc    = m.control()
+	)
+	for directive, limit := range map[string]time.Duration{
+		"stale-while-revalidate": c.revalidateLimit,
+		"stale-if-error":         c.errorLimit,
+	} {
+		if limit < 0 {
 			continue
 		}
-		c.mu.Lock()
-		for k, i := range c.items {
-			if n.Sub(i.when) < wait {
-				continue
-			}
-			delete(c.items, k)
-			os.Remove(i.file)
+		if d, ok := cc.duration(directive); ok {
+			limit = d
+		}
+		if limit > grace {
+			grace = limit
 		}
-		c.mu.Unlock()
 	}
+	return grace
 }
 
-func (c *filecache) key(str string) uint32 {
-	return adler32.Checksum([]byte(str))
+func (c *cache) evict(key string) {
+	atomic.AddInt64(&c.evictions, 1)
+	if m, err := c.lookup(key); err == nil {
+		for _, k := range m.Keys {
+			c.drop(k)
+		}
+		if m.Parent != "" {
+			c.unmark(m.Parent, key)
+		}
+	}
+	c.remove(key)
 }
 
-type item struct {
-	when  time.Time
-	file  string
-	dtype string
+func (c *cache) drop(key string) error {
+	c.lru.remove(key)
+	return c.remove(key)
 }
 
-func makeItem(file, dtype string) *item {
-	return &item{
-		when:  time.Now(),
-		file:  file,
-		dtype: dtype,
+func (c *cache) revalidate(req *http.Request, res *http.Response, do DoFunc) DoFunc {
+	key, m, err := c.find(req)
+	if err != nil || !m.validates(req) {
+		return func(_ string, _ io.Reader) error {
+			return errMissing
+		}
+	}
+	return func(_ string, _ io.Reader) error {
+		c.refresh(m, res.Header, time.Now())
+		c.update(key, m)
+		return c.serve(req, key, m, do)
 	}
 }
 
-func (i item) isExpired(ttl time.Duration) bool {
-	return ttl <= 0 || time.Since(i.when) >= ttl
+func (c *cache) key(req *http.Request) string {
+	return hashKey(c.keyfn(req))
 }
 
-func (i item) get(do DoFunc) error {
-	r, err := os.Open(i.file)
-	if err != nil {
-		return err
+func (c *cache) variant(req *http.Request, names []string) string {
+	var str strings.Builder
+	str.WriteString(c.keyfn(req))
+	for _, n := range names {
+		str.WriteString("\n")
+		str.WriteString(n)
+		str.WriteString(": ")
+		str.WriteString(strings.Join(req.Header.Values(n), ", "))
 	}
-	defer r.Close()
-	return do(i.dtype, r)
+	return hashKey(str.String())
 }
 
-type noopcache struct{}
+func isSafe(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
 
-func (noopcache) Get(_ string, _ DoFunc) error {
-	return errMissing
+func hashKey(str string) string {
+	sum := sha256.Sum256([]byte(str))
+	return hex.EncodeToString(sum[:])
 }
 
-func (noopcache) Do(_ *urllib.URL, do DoFunc) DoFunc {
-	return do
+func (c *cache) isFresh(m *meta, req *http.Request, now time.Time) bool {
+	age := m.age(now)
+	if c.mode == ModeTTL {
+		return age < m.Lifetime
+	}
+	rc := requestControl(req)
+	if rc.has("no-cache") || m.control().has("no-cache") {
+		return false
+	}
+	if d, ok := rc.duration("max-age"); ok && age > d {
+		return false
+	}
+	if d, ok := rc.duration("min-fresh"); ok && m.Lifetime-age < d {
+		return false
+	}
+	if age < m.Lifetime {
+		return true
+	}
+	if !rc.has("max-stale") || m.control().has("must-revalidate") {
+		return false
+	}
+	d, ok := rc.duration("max-stale")
+	return !ok || age-m.Lifetime <= d
 }
 
-const (
-	dataBucket = "data"
-	typeBucket = "type"
-	timeBucket = "time"
-)
+func (c *cache) canServeStale(m *meta, req *http.Request, directive string, limit time.Duration, now time.Time) bool {
+	if limit < 0 {
+		return false
+	}
+	rc := requestControl(req)
+	if rc.has("no-cache") || rc.has("max-age") || rc.has("min-fresh") {
+		return false
+	}
+	cc := m.control()
+	if d, ok := cc.duration(directive); ok {
+		limit = d
+	} else if cc.has("must-revalidate") || cc.has("no-cache") || (c.mode == ModeShared && cc.has("proxy-revalidate")) {
+		return false
+	}
+	return m.age(now)-m.Lifetime <= limit
+}
 
-type boltcache struct {
-	db  *bolt.DB
-	ttl time.Duration
+func (c *cache) markPending(key string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if when, ok := c.pending[key]; ok && now.Sub(when) < time.Minute {
+		return false
+	}
+	c.pending[key] = now
+	return true
 }
 
-func BoltCache(ttl time.Duration) (Cache, error) {
-	os.Remove(cacheFile)
-	db, err := bolt.Open(cacheFile, 0644, nil)
-	if err != nil {
-		return nil, err
+func (c *cache) clearPending(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, key)
+}
+
+func (c *cache) isStorable(req *http.Request, res *http.Response) bool {
+	if c.mode == ModeTTL {
+		return c.ttl > 0
 	}
-	c := boltcache{
-		db:  db,
-		ttl: ttl,
+	if req.Method != http.MethodGet {
+		return false
 	}
-	err = c.db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(dataBucket))
-		if err == nil {
-			_, err = tx.CreateBucketIfNotExists([]byte(typeBucket))
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo:
+	default:
+		return false
+	}
+	var (
+		rc = requestControl(req)
+		cc = responseControl(res.Header)
+	)
+	if rc.has("no-store") || cc.has("no-store") {
+		return false
+	}
+	if res.Header.Get("vary") == "*" {
+		return false
+	}
+	if c.mode == ModeShared {
+		if cc.has("private") {
+			return false
 		}
-		if err == nil {
-			_, err = tx.CreateBucketIfNotExists([]byte(timeBucket))
+		if req.Header.Get("authorization") != "" && !cc.has("public") && !cc.has("s-maxage") && !cc.has("must-revalidate") {
+			return false
 		}
-		return err
-	})
-	return &c, err
+	}
+	if c.lifetime(res.Header, cc) > 0 || c.explicit(res.Header, cc) {
+		return true
+	}
+	return res.Header.Get("etag") != "" || res.Header.Get("last-modified") != ""
 }
 
-func (b *boltcache) Close() error {
-	b.db.Close()
-	return os.Remove(cacheFile)
+func (c *cache) explicit(h http.Header, cc cacheControl) bool {
+	if c.mode == ModeShared && cc.has("s-maxage") {
+		return true
+	}
+	return cc.has("max-age") || h.Get("expires") != ""
 }
 
-func (b *boltcache) Get(url string, do DoFunc) error {
-	if b.ttl <= 0 {
-		return errMissing
+func (c *cache) makeMeta(req *http.Request, res *http.Response, now time.Time) *meta {
+	m := meta{
+		URL:    req.URL.String(),
+		Header: make(http.Header),
+	}
+	if vary := varyHeaders(res.Header); len(vary) > 0 && c.mode != ModeTTL {
+		m.Vary = make(http.Header)
+		for _, k := range vary {
+			m.Vary[k] = req.Header.Values(k)
+		}
 	}
-	return b.get(url, do)
+	c.refresh(&m, res.Header, now)
+	return &m
 }
 
-func (b *boltcache) Do(loc *urllib.URL, do DoFunc) DoFunc {
-	if b.ttl <= 0 {
-		return do
+func (c *cache) refresh(m *meta, h http.Header, now time.Time) {
+	hs := m.Header.Clone()
+	if hs == nil {
+		hs = make(http.Header)
 	}
-	url := loc.String()
-	return func(ct string, r io.Reader) error {
-		return b.do(url, do, ct, r)
+	hs.Del("date")
+	hs.Del("age")
+	for _, k := range storedHeaders {
+		if vs := h.Values(k); len(vs) > 0 {
+			hs[http.CanonicalHeaderKey(k)] = vs
+		}
+	}
+	m.Header = hs
+	m.Stored = now
+	if c.mode == ModeTTL {
+		m.Lifetime = c.ttl
+		return
 	}
+	m.Lifetime = c.lifetime(m.Header, m.control())
+	m.Age = initialAge(m.Header, now)
 }
 
-func (b *boltcache) get(url string, do DoFunc) error {
-	key := b.key(url)
-	err := b.db.View(func(tx *bolt.Tx) error {
-		var (
-			bk   = tx.Bucket([]byte(timeBucket))
-			vs   []byte
-			when time.Time
-		)
-		if err := when.UnmarshalBinary(bk.Get(key)); err != nil {
-			return errMissing
+func (c *cache) lifetime(h http.Header, cc cacheControl) time.Duration {
+	if c.mode == ModeShared {
+		if d, ok := cc.duration("s-maxage"); ok {
+			return d
+		}
+	}
+	if d, ok := cc.duration("max-age"); ok {
+		return d
+	}
+	if str := h.Get("expires"); str != "" {
+		when, err := http.ParseTime(str)
+		if err != nil {
+			return 0
 		}
-		if time.Since(when) >= b.ttl {
-			return errExpired
+		date, err := http.ParseTime(h.Get("date"))
+		if err != nil {
+			date = time.Now()
 		}
+		return when.Sub(date)
+	}
+	return c.ttl
+}
+
+var storedHeaders = []string{
+	"cache-control",
+	"pragma",
+	"expires",
+	"date",
+	"age",
+	"etag",
+	"last-modified",
+	"vary",
+}
+
+type meta struct {
+	Key      string        `json:"key"`
+	URL      string        `json:"url"`
+	Type     string        `json:"type"`
+	Stored   time.Time     `json:"stored"`
+	Age      time.Duration `json:"age"`
+	Lifetime time.Duration `json:"lifetime"`
+	Size     int64         `json:"size"`
+	Sum      uint32        `json:"sum"`
+	Header   http.Header   `json:"header,omitempty"`
+	Vary     http.Header   `json:"vary,omitempty"`
+	Variants []string      `json:"variants,omitempty"`
+	Keys     []string      `json:"keys,omitempty"`
+	Parent   string        `json:"parent,omitempty"`
+	Codec    string        `json:"codec,omitempty"`
+}
 
-		bk = tx.Bucket([]byte(dataBucket))
-		vs = bk.Get(key)
-		bk = tx.Bucket([]byte(typeBucket))
-		err := do(string(bk.Get(key)), bytes.NewReader(vs))
+func (m *meta) clone() meta {
+	x := *m
+	x.Header = m.Header.Clone()
+	x.Vary = m.Vary.Clone()
+	x.Variants = append([]string(nil), m.Variants...)
+	x.Keys = append([]string(nil), m.Keys...)
+	return x
+}
+
+func (m *meta) weight() int64 {
+	if len(m.Variants) == 0 {
+		return m.Size
+	}
+	buf, _ := json.Marshal(m)
+	return int64(len(buf))
+}
+
+func (m *meta) age(now time.Time) time.Duration {
+	return m.Age + now.Sub(m.Stored)
+}
+
+func (m *meta) verify(r io.Reader) error {
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return nil
+	}
+	sum := makeDigest()
+	if _, err := io.Copy(sum, r); err != nil {
 		return err
-	})
+	}
+	if sum.size != m.Size || sum.Sum32() != m.Sum {
+		return errCorrupted
+	}
+	_, err := s.Seek(0, io.SeekStart)
 	return err
 }
 
-func (b *boltcache) do(url string, do DoFunc, ct string, r io.Reader) error {
+func (m *meta) control() cacheControl {
+	return responseControl(m.Header)
+}
+
+func (m *meta) condition(req *http.Request) bool {
 	var (
-		buf bytes.Buffer
-		key = b.key(url)
-		now = time.Now()
+		etag = m.Header.Get("etag")
+		last = m.Header.Get("last-modified")
 	)
-	errd := do(ct, io.TeeReader(r, &buf))
-	errb := b.db.Update(func(tx *bolt.Tx) error {
-		var (
-			bk    = tx.Bucket([]byte(timeBucket))
-			ns, _ = now.MarshalBinary()
-		)
-		if err := bk.Put(key, ns); err != nil {
-			return err
+	if etag != "" {
+		req.Header.Set("if-none-match", etag)
+	}
+	if last != "" {
+		req.Header.Set("if-modified-since", last)
+	}
+	return etag != "" || last != ""
+}
+
+func (m *meta) revalidable() bool {
+	return m.Header.Get("etag") != "" || m.Header.Get("last-modified") != ""
+}
+
+func (m *meta) validates(req *http.Request) bool {
+	if etag := m.Header.Get("etag"); etag != "" && req.Header.Get("if-none-match") == etag {
+		return true
+	}
+	last := m.Header.Get("last-modified")
+	return last != "" && req.Header.Get("if-modified-since") == last
+}
+
+func (m *meta) matches(req *http.Request) bool {
+	for k, vs := range m.Vary {
+		if !equalValues(vs, req.Header.Values(k)) {
+			return false
 		}
-		bk = tx.Bucket([]byte(dataBucket))
-		if err := bk.Put(key, buf.Bytes()); err != nil {
-			return err
+	}
+	return true
+}
+
+type digest struct {
+	hash.Hash32
+	size int64
+}
+
+func makeDigest() *digest {
+	return &digest{
+		Hash32: crc32.NewIEEE(),
+	}
+}
+
+func (d *digest) Write(b []byte) (int, error) {
+	d.size += int64(len(b))
+	return d.Hash32.Write(b)
+}
+
+func encodeWith(codec string, w io.Writer) (io.WriteCloser, error) {
+	switch codec {
+	case "":
+		return nopWriteCloser{w}, nil
+	case encgzip:
+		return gzip.NewWriter(w), nil
+	case encflate:
+		return flate.NewWriter(w, flate.DefaultCompression)
+	default:
+		return nil, errCodec
+	}
+}
+
+func decodeWith(codec string, r io.Reader) (io.Reader, error) {
+	switch codec {
+	case "":
+		return r, nil
+	case encgzip:
+		return gzip.NewReader(r)
+	case encflate:
+		return flate.NewReader(r), nil
+	default:
+		return nil, errCodec
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+type limitWriter struct {
+	io.Writer
+	limit   int64
+	written int64
+	err     error
+}
+
+func (w *limitWriter) Write(b []byte) (int, error) {
+	w.written += int64(len(b))
+	if w.failed() {
+		return len(b), nil
+	}
+	if _, err := w.Writer.Write(b); err != nil {
+		w.err = err
+	}
+	return len(b), nil
+}
+
+func (w *limitWriter) drain(r io.Reader) error {
+	buf := make([]byte, 32<<10)
+	for !w.failed() {
+		n, err := r.Read(buf)
+		w.Write(buf[:n])
+		if err == io.EOF {
+			return nil
 		}
-		bk = tx.Bucket([]byte(typeBucket))
-		if err := bk.Put(key, []byte(ct)); err != nil {
+		if err != nil {
 			return err
 		}
-		return nil
-	})
-	if errd != nil {
-		return errd
 	}
-	return errb
+	return nil
 }
 
-func (b *boltcache) key(str string) []byte {
-	var (
-		xs = []byte(str)
-		bs = make([]byte, 8)
-	)
-	binary.BigEndian.PutUint64(bs, xxh.Sum64(xs, 0))
-	return bs
+func (w *limitWriter) failed() bool {
+	return w.err != nil || (w.limit > 0 && w.written > w.limit)
+}
+
+type noopcache struct{}
+
+func (noopcache) Get(_ *http.Request, _ DoFunc) error {
+	return errMissing
+}
+
+func (noopcache) Stale(_ *http.Request, _ DoFunc) error {
+	return errMissing
+}
+
+func (noopcache) Do(_ *http.Request, _ *http.Response, do DoFunc) DoFunc {
+	return do
+}
+
+func (noopcache) Invalidate(_ *http.Request, _ *http.Response) {}
+
+func (noopcache) Delete(_ string) error {
+	return nil
+}
+
+func (noopcache) Purge() error {
+	return nil
+}
+
+func (noopcache) Stats() CacheStats {
+	return CacheStats{}
+}
+
+func (noopcache) Walk(_ func(CacheEntry) error) error {
+	return nil
+}
+
+func (noopcache) Close() error {
+	return nil
 }
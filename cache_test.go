@@ -0,0 +1,170 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheInvalidateVariants(t *testing.T) {
+	var version int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			atomic.AddInt32(&version, 1)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("cache-control", "max-age=60")
+		w.Header().Set("vary", "accept-language")
+		fmt.Fprintf(w, "v%d-%s", atomic.LoadInt32(&version), r.Header.Get("accept-language"))
+	}))
+	defer srv.Close()
+
+	caches := map[string]Cache{
+		"memory": MemoryCache(0, time.Minute, WithCacheMode(ModePrivate)),
+		"file":   FileCache(t.TempDir(), 0, time.Minute, WithCacheMode(ModePrivate)),
+	}
+	for name, cache := range caches {
+		atomic.StoreInt32(&version, 1)
+		c := NewClient()
+		c.Cache = cache
+
+		get := func(lang, want string) {
+			t.Helper()
+			if err := c.GetWith(srv.URL, expectBody(t, want), Header("accept-language", lang)); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+		get("en", "v1-en")
+		get("fr", "v1-fr")
+		get("fr", "v1-fr")
+		if err := c.PostJSON(srv.URL, 1, nil); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		get("en", "v2-en")
+		get("fr", "v2-fr")
+		cache.Close()
+	}
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		w.Header().Set("cache-control", "max-age=0, stale-while-revalidate=60")
+		fmt.Fprintf(w, "v%d", n)
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithMemoryCache(0, time.Minute, WithCacheMode(ModePrivate), WithStaleWhileRevalidate(0)))
+	defer c.Close()
+
+	if err := c.GetWith(srv.URL, expectBody(t, "v1")); err != nil {
+		t.Fatal(err)
+	}
+	stored := storedAt(c)
+	if err := c.GetWith(srv.URL, expectBody(t, "v1")); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000 && storedAt(c).Equal(stored); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Fatalf("upstream called %d times, want 2", n)
+	}
+	if err := c.GetWith(srv.URL, expectBody(t, "v2")); err != nil {
+		t.Fatal(err)
+	}
+	if s := c.Stats(); s.Stale != 2 {
+		t.Errorf("stale serves %d, want 2", s.Stale)
+	}
+}
+
+func TestCacheStaleIfError(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("cache-control", "max-age=0, stale-if-error=60")
+		io.WriteString(w, "payload")
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithMemoryCache(0, time.Minute, WithCacheMode(ModePrivate), WithStaleIfError(0)))
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := c.GetWith(srv.URL, expectBody(t, "payload")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := c.Stats(); s.Stale != 2 {
+		t.Errorf("stale serves %d, want 2", s.Stale)
+	}
+}
+
+func TestCacheRevalidateMissing(t *testing.T) {
+	var (
+		full  int32
+		purge func()
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("cache-control", "max-age=0")
+		w.Header().Set("etag", `"v1"`)
+		if r.Header.Get("if-none-match") == `"v1"` {
+			purge()
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&full, 1)
+		io.WriteString(w, "payload")
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithMemoryCache(0, time.Minute, WithCacheMode(ModePrivate)))
+	defer c.Close()
+	purge = func() {
+		c.Purge()
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := c.GetWith(srv.URL, expectBody(t, "payload")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&full); n != 2 {
+		t.Errorf("full responses %d, want 2", n)
+	}
+}
+
+func storedAt(c Client) time.Time {
+	var when time.Time
+	c.Walk(func(e CacheEntry) error {
+		when = e.Stored
+		return nil
+	})
+	return when
+}
+
+func TestCacheUnknownCodec(t *testing.T) {
+	c := NewClient(WithMemoryCache(0, time.Minute, WithCompression("zstd")))
+	defer c.Close()
+	if err := c.GetWith("http://localhost", nil); !errors.Is(err, errCodec) {
+		t.Errorf("client: got %v, want %v", err, errCodec)
+	}
+
+	opts := BoltCacheOptions{
+		Path: filepath.Join(t.TempDir(), "cache.db"),
+	}
+	if _, err := OpenBoltCache(opts, time.Minute, WithCompression("zstd")); !errors.Is(err, errCodec) {
+		t.Errorf("bolt: got %v, want %v", err, errCodec)
+	}
+}
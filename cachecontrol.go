@@ -0,0 +1,121 @@
+package fetch
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type cacheControl map[string]string
+
+func requestControl(req *http.Request) cacheControl {
+	return responseControl(req.Header)
+}
+
+func responseControl(h http.Header) cacheControl {
+	cc := parseCacheControl(h.Values("cache-control"))
+	if len(cc) == 0 && hasToken(h.Values("pragma"), "no-cache") {
+		cc["no-cache"] = ""
+	}
+	return cc
+}
+
+func parseCacheControl(values []string) cacheControl {
+	cc := make(cacheControl)
+	for _, str := range values {
+		for _, str := range splitList(str) {
+			name, value := str, ""
+			if x := strings.Index(str, "="); x > 0 {
+				name, value = str[:x], strings.Trim(strings.TrimSpace(str[x+1:]), "\"")
+			}
+			name = strings.ToLower(strings.TrimSpace(name))
+			if _, ok := cc[name]; ok {
+				continue
+			}
+			cc[name] = value
+		}
+	}
+	return cc
+}
+
+func (c cacheControl) has(name string) bool {
+	_, ok := c[name]
+	return ok
+}
+
+func (c cacheControl) duration(name string) (time.Duration, bool) {
+	str, ok := c[name]
+	if !ok || str == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(str, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+func initialAge(h http.Header, now time.Time) time.Duration {
+	var age time.Duration
+	if date, err := http.ParseTime(h.Get("date")); err == nil && now.After(date) {
+		age = now.Sub(date)
+	}
+	if n, err := strconv.ParseInt(h.Get("age"), 10, 64); err == nil && n > 0 {
+		if d := time.Duration(n) * time.Second; d > age {
+			age = d
+		}
+	}
+	return age
+}
+
+func varyHeaders(h http.Header) []string {
+	var list []string
+	for _, str := range h.Values("vary") {
+		for _, str := range splitList(str) {
+			list = append(list, http.CanonicalHeaderKey(str))
+		}
+	}
+	return list
+}
+
+func splitList(str string) []string {
+	var (
+		list  []string
+		quote bool
+		last  int
+	)
+	for i := 0; i < len(str); i++ {
+		switch str[i] {
+		case '"':
+			quote = !quote
+		case ',':
+			if quote {
+				continue
+			}
+			if s := strings.TrimSpace(str[last:i]); s != "" {
+				list = append(list, s)
+			}
+			last = i + 1
+		}
+	}
+	if s := strings.TrimSpace(str[last:]); s != "" {
+		list = append(list, s)
+	}
+	return list
+}
+
+func hasToken(values []string, token string) bool {
+	for _, str := range values {
+		for _, str := range splitList(str) {
+			if strings.EqualFold(str, token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func equalValues(left, right []string) bool {
+	return strings.Join(left, ",") == strings.Join(right, ",")
+}
@@ -5,15 +5,11 @@ import (
 	"compress/gzip"
 	"context"
 	"crypto/tls"
-	"fmt"
-	"hash/adler32"
 	"io"
 	"net"
 	"net/http"
-	urllib "net/url"
 	"path"
 	"time"
-	// "github.com/midbel/try"
 )
 
 var DefaultClient Client
@@ -30,6 +26,12 @@ func WithTimeout(wait time.Duration) Option {
 	}
 }
 
+func WithRequestTimeout(wait time.Duration) Option {
+	return func(c *Client) {
+		c.wait = wait
+	}
+}
+
 func WithHook(fn HookFunc) Option {
 	return func(c *Client) {
 		c.hooks = append(c.hooks, fn)
@@ -48,12 +50,6 @@ func WithCredentials(u, p string) Option {
 	}
 }
 
-func WithProxy(addr string) Option {
-	return func(c *Client) {
-
-	}
-}
-
 func WithTransform(fn TransformFunc) Option {
 	return func(c *Client) {
 		c.transform = fn
@@ -76,40 +72,75 @@ func WithConfig(cfg *tls.Config) Option {
 	}
 }
 
-func WithFileCache(dir string, size int, ttl time.Duration) Option {
+func WithFileCache(dir string, size int, ttl time.Duration, options ...CacheOption) Option {
+	return func(c *Client) {
+		c.Cache = FileCache(dir, size, ttl, options...)
+		if err := cacheError(c.Cache); err != nil {
+			c.err = err
+		}
+	}
+}
+
+func WithMemoryCache(size int64, ttl time.Duration, options ...CacheOption) Option {
+	return func(c *Client) {
+		c.Cache = MemoryCache(size, ttl, options...)
+		if err := cacheError(c.Cache); err != nil {
+			c.err = err
+		}
+	}
+}
+
+func WithBoltCache(ttl time.Duration, options ...CacheOption) Option {
 	return func(c *Client) {
-		c.Cache = FileCache(dir, size, ttl)
+		bc, err := BoltCache(ttl, options...)
+		if err != nil {
+			c.err = err
+			return
+		}
+		c.Cache = bc
 	}
 }
 
-func WithBoltCache(ttl time.Duration) Option {
+func WithBoltCacheOptions(opts BoltCacheOptions, ttl time.Duration, options ...CacheOption) Option {
 	return func(c *Client) {
-		bc, err := BoltCache(ttl)
-		if err == nil {
-			c.Cache = bc
+		bc, err := OpenBoltCache(opts, ttl, options...)
+		if err != nil {
+			c.err = err
+			return
 		}
+		c.Cache = bc
 	}
 }
 
-// func WithRetry(attempt int) Option {
-// 	return func(c *Client) {
-// 		c.retry = attempt
-// 	}
-// }
+func WithRetry(retries int) Option {
+	return WithRetryPolicy(DefaultRetry(retries))
+}
+
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
 
 type HookFunc func(http.Header) error
 
 type Client struct {
 	client http.Client
 
-	headers   http.Header
-	hooks     []HookFunc
-	transform TransformFunc
+	headers     http.Header
+	hooks       []HookFunc
+	transform   TransformFunc
+	middlewares []Middleware
 
 	addDefault bool
 	user       string
 	pass       string
-	// retry      int
+	wait       time.Duration
+	retry      RetryPolicy
+	proxy      ProxyFunc
+	noproxy    []string
+	flight     *flight
+	err        error
 
 	Cache
 }
@@ -145,181 +176,362 @@ func NewClient(options ...Option) Client {
 	for _, fn := range options {
 		fn(&c)
 	}
+	if t, ok := c.client.Transport.(*http.Transport); ok && c.proxy != nil {
+		t.Proxy = bypassProxy(c.proxy, c.noproxy)
+	}
+	if len(c.middlewares) > 0 {
+		c.client.Transport = Chain(c.client.Transport, c.middlewares...)
+	}
 	return c
 }
 
-func (c *Client) Get(url string, out interface{}) error {
-	return c.doGet(url, decodeBody(out))
+func (c *Client) Get(url string, out interface{}, opts ...RequestOption) error {
+	return c.GetContext(context.Background(), url, out, opts...)
+}
+
+func (c *Client) GetContext(ctx context.Context, url string, out interface{}, opts ...RequestOption) error {
+	return c.doGet(ctx, url, decodeBody(out), opts...)
+}
+
+func (c *Client) GetWith(url string, do DoFunc, opts ...RequestOption) error {
+	return c.GetWithContext(context.Background(), url, do, opts...)
+}
+
+func (c *Client) GetWithContext(ctx context.Context, url string, do DoFunc, opts ...RequestOption) error {
+	return c.doGet(ctx, url, do, opts...)
 }
 
-func (c *Client) GetWith(url string, do DoFunc) error {
-	return c.doGet(url, do)
+func (c *Client) Query(url, query string, vars Values, out interface{}, opts ...RequestOption) error {
+	return c.QueryContext(context.Background(), url, query, vars, out, opts...)
 }
 
-func (c *Client) Query(url, query string, vars Values, out interface{}) error {
-	q := makeQuery(query, vars)
+func (c *Client) QueryContext(ctx context.Context, url, query string, vars Values, out interface{}, opts ...RequestOption) error {
 	r := struct {
-		Data   interface{}
-		Errors []struct {
-			Message string
-		}
+		Data interface{}
 	}{
 		Data: out,
 	}
-	err := c.doQuery(http.MethodPost, url, query, q, decodeBody(&r))
-	if z := len(r.Errors); err == nil && z > 0 {
-		err = fmt.Errorf("query returned %d error(s): %s,...", z, r.Errors[0].Message)
-	}
-	return err
+	return c.doQuery(ctx, url, query, vars, decodeBody(&r), opts...)
 }
 
-func (c *Client) QueryWith(url, query string, vars Values, do DoFunc) error {
-	q := makeQuery(query, vars)
-	r := struct {
-		Data   interface{}
-		Errors []struct {
-			Message string
-		}
-	}{}
-	err := c.doQuery(http.MethodPost, url, query, q, do)
-	if z := len(r.Errors); err == nil && z > 0 {
-		err = fmt.Errorf("query returned %d error(s): %s,...", z, r.Errors[0].Message)
+func (c *Client) QueryWith(url, query string, vars Values, do DoFunc, opts ...RequestOption) error {
+	return c.QueryWithContext(context.Background(), url, query, vars, do, opts...)
+}
+
+func (c *Client) QueryWithContext(ctx context.Context, url, query string, vars Values, do DoFunc, opts ...RequestOption) error {
+	return c.doQuery(ctx, url, query, vars, do, opts...)
+}
+
+func (c *Client) Follow(url string, rel RelType, do DoFunc, opts ...RequestOption) error {
+	return c.FollowContext(context.Background(), url, rel, do, opts...)
+}
+
+func (c *Client) FollowContext(ctx context.Context, url string, rel RelType, do DoFunc, opts ...RequestOption) error {
+	return c.doFollow(ctx, url, rel, do, opts...)
+}
+
+func (c *Client) PostJSON(url string, in, out interface{}, opts ...RequestOption) error {
+	return c.PostJSONContext(context.Background(), url, in, out, opts...)
+}
+
+func (c *Client) PostJSONContext(ctx context.Context, url string, in, out interface{}, opts ...RequestOption) error {
+	return c.doJSON(ctx, http.MethodPost, url, in, out, opts...)
+}
+
+func (c *Client) PostXML(url string, in, out interface{}, opts ...RequestOption) error {
+	return c.PostXMLContext(context.Background(), url, in, out, opts...)
+}
+
+func (c *Client) PostXMLContext(ctx context.Context, url string, in, out interface{}, opts ...RequestOption) error {
+	return c.doXML(ctx, http.MethodPost, url, in, out, opts...)
+}
+
+func (c *Client) PostWithBody(url string, r io.Reader, ct string, do DoFunc, opts ...RequestOption) error {
+	return c.PostWithBodyContext(context.Background(), url, r, ct, do, opts...)
+}
+
+func (c *Client) PostWithBodyContext(ctx context.Context, url string, r io.Reader, ct string, do DoFunc, opts ...RequestOption) error {
+	return c.doBody(ctx, http.MethodPost, url, makeBody(ct, r), do, opts...)
+}
+
+func (c *Client) PostMultipart(url string, m *Multipart, out interface{}, opts ...RequestOption) error {
+	return c.PostMultipartContext(context.Background(), url, m, out, opts...)
+}
+
+func (c *Client) PostMultipartContext(ctx context.Context, url string, m *Multipart, out interface{}, opts ...RequestOption) error {
+	r, ct := m.Open()
+	defer r.Close()
+
+	var do DoFunc
+	if out != nil {
+		do = decodeBody(out)
 	}
-	return err
+	return c.doBody(ctx, http.MethodPost, url, makeBody(ct, r), do, opts...)
 }
 
-func (c *Client) Follow(url string, rel RelType, do DoFunc) error {
-	return c.doFollow(url, rel, do)
+func (c *Client) PostForm(url string, in, out interface{}, opts ...RequestOption) error {
+	return c.PostFormContext(context.Background(), url, in, out, opts...)
 }
 
-func (c *Client) PostJSON(url string, in, out interface{}) error {
-	return c.doJSON(http.MethodPost, url, in, out)
+func (c *Client) PostFormContext(ctx context.Context, url string, in, out interface{}, opts ...RequestOption) error {
+	return c.doForm(ctx, http.MethodPost, url, in, out, opts...)
 }
 
-func (c *Client) PostXML(url string, in, out interface{}) error {
-	return c.doXML(http.MethodPost, url, in, out)
+func (c *Client) PutJSON(url string, in, out interface{}, opts ...RequestOption) error {
+	return c.PutJSONContext(context.Background(), url, in, out, opts...)
 }
 
-// func (c *Client) PostWithBody(url string, r io.Reader, do DoFunc) error {
-// 	return nil
-// }
+func (c *Client) PutJSONContext(ctx context.Context, url string, in, out interface{}, opts ...RequestOption) error {
+	return c.doJSON(ctx, http.MethodPut, url, in, out, opts...)
+}
 
-func (c *Client) PutJSON(url string, in, out interface{}) error {
-	return c.doJSON(http.MethodPut, url, in, out)
+func (c *Client) PutXML(url string, in, out interface{}, opts ...RequestOption) error {
+	return c.PutXMLContext(context.Background(), url, in, out, opts...)
 }
 
-func (c *Client) PutXML(url string, in, out interface{}) error {
-	return c.doXML(http.MethodPut, url, in, out)
+func (c *Client) PutXMLContext(ctx context.Context, url string, in, out interface{}, opts ...RequestOption) error {
+	return c.doXML(ctx, http.MethodPut, url, in, out, opts...)
 }
 
-// func (c *Client) PutWithBody(url string, r io.Reader, do DoFunc) error {
-// 	return nil
-// }
+func (c *Client) PutWithBody(url string, r io.Reader, ct string, do DoFunc, opts ...RequestOption) error {
+	return c.PutWithBodyContext(context.Background(), url, r, ct, do, opts...)
+}
 
-func (c *Client) PatchJSON(url string, in, out interface{}) error {
-	return c.doJSON(http.MethodPatch, url, in, out)
+func (c *Client) PutWithBodyContext(ctx context.Context, url string, r io.Reader, ct string, do DoFunc, opts ...RequestOption) error {
+	return c.doBody(ctx, http.MethodPut, url, makeBody(ct, r), do, opts...)
 }
 
-func (c *Client) PatchXML(url string, in, out interface{}) error {
-	return c.doXML(http.MethodPatch, url, in, out)
+func (c *Client) PutForm(url string, in, out interface{}, opts ...RequestOption) error {
+	return c.PutFormContext(context.Background(), url, in, out, opts...)
 }
 
-// func (c *Client) PatchWithBody(url string, r io.Reader, do DoFunc) error {
-// 	return nil
-// }
+func (c *Client) PutFormContext(ctx context.Context, url string, in, out interface{}, opts ...RequestOption) error {
+	return c.doForm(ctx, http.MethodPut, url, in, out, opts...)
+}
 
-func (c *Client) Delete(url string, out interface{}) error {
+func (c *Client) PatchJSON(url string, in, out interface{}, opts ...RequestOption) error {
+	return c.PatchJSONContext(context.Background(), url, in, out, opts...)
+}
+
+func (c *Client) PatchJSONContext(ctx context.Context, url string, in, out interface{}, opts ...RequestOption) error {
+	return c.doJSON(ctx, http.MethodPatch, url, in, out, opts...)
+}
+
+func (c *Client) PatchXML(url string, in, out interface{}, opts ...RequestOption) error {
+	return c.PatchXMLContext(context.Background(), url, in, out, opts...)
+}
+
+func (c *Client) PatchXMLContext(ctx context.Context, url string, in, out interface{}, opts ...RequestOption) error {
+	return c.doXML(ctx, http.MethodPatch, url, in, out, opts...)
+}
+
+func (c *Client) PatchWithBody(url string, r io.Reader, ct string, do DoFunc, opts ...RequestOption) error {
+	return c.PatchWithBodyContext(context.Background(), url, r, ct, do, opts...)
+}
+
+func (c *Client) PatchWithBodyContext(ctx context.Context, url string, r io.Reader, ct string, do DoFunc, opts ...RequestOption) error {
+	return c.doBody(ctx, http.MethodPatch, url, makeBody(ct, r), do, opts...)
+}
+
+func (c *Client) Delete(url string, out interface{}, opts ...RequestOption) error {
+	return c.DeleteContext(context.Background(), url, out, opts...)
+}
+
+func (c *Client) DeleteContext(ctx context.Context, url string, out interface{}, opts ...RequestOption) error {
 	var do DoFunc
 	if out != nil {
 		do = decodeBody(out)
 	}
-	return c.doDelete(url, do)
+	return c.doDelete(ctx, url, do, opts...)
+}
+
+func (c *Client) DeleteJSON(url string, in, out interface{}, opts ...RequestOption) error {
+	return c.DeleteJSONContext(context.Background(), url, in, out, opts...)
+}
+
+func (c *Client) DeleteJSONContext(ctx context.Context, url string, in, out interface{}, opts ...RequestOption) error {
+	return c.doJSON(ctx, http.MethodDelete, url, in, out, opts...)
 }
 
-func (c *Client) DeleteJSON(url string, in, out interface{}) error {
-	return c.doJSON(http.MethodDelete, url, in, out)
+func (c *Client) Options(url string, opts ...RequestOption) (http.Header, int, error) {
+	return c.OptionsContext(context.Background(), url, opts...)
 }
 
-func (c *Client) Options(url string) (http.Header, error) {
-	return nil, nil
+func (c *Client) OptionsContext(ctx context.Context, url string, opts ...RequestOption) (http.Header, int, error) {
+	return c.doHeader(ctx, http.MethodOptions, url, opts...)
 }
 
-func (c *Client) Head(url string) (http.Header, error) {
-	return nil, nil
+func (c *Client) Head(url string, opts ...RequestOption) (http.Header, int, error) {
+	return c.HeadContext(context.Background(), url, opts...)
 }
 
-func (c *Client) doDelete(url string, do DoFunc) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+func (c *Client) HeadContext(ctx context.Context, url string, opts ...RequestOption) (http.Header, int, error) {
+	return c.doHeader(ctx, http.MethodHead, url, opts...)
+}
+
+func (c *Client) doDelete(ctx context.Context, url string, do DoFunc, opts ...RequestOption) error {
+	return c.doBody(ctx, http.MethodDelete, url, emptyBody(), do, opts...)
+}
+
+func (c *Client) doHeader(ctx context.Context, meth, url string, opts ...RequestOption) (http.Header, int, error) {
+	cl, err := c.makeCall(url, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	ctx, cancel := cl.context(ctx)
 	defer cancel()
 
-	res, err := c.execute(ctx, http.MethodDelete, url, emptyBody())
+	res, err := c.execute(ctx, meth, cl.url, emptyBody(), cl)
+	if err != nil {
+		return nil, 0, err
+	}
+	err = c.decodeResponse(res, nil)
+	return res.Header, res.StatusCode, err
+}
+
+func (c *Client) doGet(ctx context.Context, url string, do DoFunc, opts ...RequestOption) error {
+	cl, err := c.makeCall(url, opts)
 	if err != nil {
 		return err
 	}
-	return c.decodeResponse(res, do)
+	ctx, cancel := cl.context(ctx)
+	defer cancel()
+
+	req, err := c.prepare(ctx, http.MethodGet, cl.url, emptyBody(), cl)
+	if err != nil {
+		return err
+	}
+	if c.flight == nil || cl.res != nil {
+		return c.fetch(req, cl, do)
+	}
+	return c.flight.do(ctx, flightKey(req), do, func(ctx context.Context, do DoFunc) error {
+		return c.fetch(req.WithContext(ctx), cl, do)
+	})
 }
 
-func (c *Client) doGet(url string, do DoFunc) error {
+func (c *Client) fetch(req *http.Request, cl call, do DoFunc) error {
 	if c.Cache != nil {
-		switch err := c.Cache.Get(url, do); err {
+		switch err := c.Cache.Get(req, do); err {
 		case errMissing, errExpired:
+		case errRevalidate:
+			go c.revalidate(req, req, cl, skipBody)
+			return nil
 		default:
 			return err
 		}
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
-	defer cancel()
-
-	res, err := c.execute(ctx, http.MethodGet, url, emptyBody())
+	res, err := c.send(req, cl)
+	if c.Cache != nil && (err != nil || res.StatusCode >= http.StatusInternalServerError) {
+		if e := c.Cache.Stale(req, do); e == nil {
+			discard(res)
+			return nil
+		}
+	}
 	if err != nil {
 		return err
 	}
-	if c.Cache != nil {
-		loc, _ := urllib.Parse(url)
-		do = c.Cache.Do(loc, do)
+	if c.Cache == nil {
+		return c.decodeResponse(res, do)
 	}
-	return c.decodeResponse(res, do)
+	err = c.decodeResponse(res, c.Cache.Do(req, res, do))
+	if err == errMissing && res.StatusCode == http.StatusNotModified {
+		return c.refetch(req, cl, do)
+	}
+	return err
 }
 
-func (c *Client) doQuery(meth, url, query string, in interface{}, do DoFunc) error {
-	loc, err := urllib.Parse(url)
+func (c *Client) refetch(req *http.Request, cl call, do DoFunc) error {
+	req, err := rewind(req.Clone(req.Context()))
 	if err != nil {
 		return err
 	}
-	loc.Path = path.Join(loc.Path, fmt.Sprintf("%x", adler32.Checksum([]byte(query))))
-	if c.Cache != nil {
-		if err := c.Cache.Get(loc.String(), do); err == nil {
-			return err
-		}
+	req.Header.Del("if-none-match")
+	req.Header.Del("if-modified-since")
+	res, err := c.send(req, cl)
+	if err != nil {
+		return err
 	}
-	bd, err := encodeJSON(in)
+	return c.decodeResponse(res, c.Cache.Do(req, res, do))
+}
+
+func (c *Client) revalidate(req, key *http.Request, cl call, do DoFunc) {
+	ctx, cancel := cl.context(context.Background())
+	defer cancel()
+
+	cl.res = nil
+	req, err := rewind(req.Clone(ctx))
+	if err != nil {
+		return
+	}
+	res, err := c.send(req, cl)
+	if err != nil {
+		return
+	}
+	c.decodeResponse(res, c.Cache.Do(key.WithContext(ctx), res, do))
+}
+
+func skipBody(_ string, _ io.Reader) error {
+	return nil
+}
+
+func (c *Client) doQuery(ctx context.Context, url, query string, vars Values, do DoFunc, opts ...RequestOption) error {
+	cl, err := c.makeCall(url, opts)
+	if err != nil {
+		return err
+	}
+	bd, err := encodeJSON(makeQuery(query, cl.operation, vars))
 	if err != nil {
 		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	ctx, cancel := cl.context(ctx)
 	defer cancel()
 
-	res, err := c.execute(ctx, meth, url, bd)
+	req, err := c.prepare(ctx, http.MethodPost, cl.url, bd, cl)
 	if err != nil {
 		return err
 	}
-	if c.Cache != nil {
-		do = c.Cache.Do(loc, do)
+	do = decodeQuery(do, cl.partial)
+
+	var key *http.Request
+	if c.Cache != nil && isCacheableQuery(query, cl.operation) {
+		key, err = queryRequest(req, query, cl.operation, vars)
+		if err != nil {
+			return err
+		}
+		switch err := c.Cache.Get(key, do); err {
+		case errMissing, errExpired:
+		case errRevalidate:
+			go c.revalidate(req, key, cl, decodeQuery(skipBody, false))
+			return nil
+		default:
+			return err
+		}
+	}
+	res, err := c.send(req, cl)
+	if err != nil {
+		return err
+	}
+	if key != nil {
+		do = c.Cache.Do(key, res, do)
 	}
 	return c.decodeResponse(res, do)
 }
 
-func (c *Client) doJSON(meth, url string, in, out interface{}) error {
+func (c *Client) doJSON(ctx context.Context, meth, url string, in, out interface{}, opts ...RequestOption) error {
 	bd, err := encodeJSON(in)
 	if err != nil {
 		return err
 	}
+	var do DoFunc
+	if out != nil {
+		do = decodeBody(out)
+	}
+	return c.doBody(ctx, meth, url, bd, do, opts...)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
-	defer cancel()
-
-	res, err := c.execute(ctx, meth, url, bd)
+func (c *Client) doForm(ctx context.Context, meth, url string, in, out interface{}, opts ...RequestOption) error {
+	bd, err := encodeForm(in)
 	if err != nil {
 		return err
 	}
@@ -327,41 +539,52 @@ func (c *Client) doJSON(meth, url string, in, out interface{}) error {
 	if out != nil {
 		do = decodeBody(out)
 	}
-	return c.decodeResponse(res, do)
+	return c.doBody(ctx, meth, url, bd, do, opts...)
 }
 
-func (c *Client) doXML(meth, url string, in, out interface{}) error {
+func (c *Client) doXML(ctx context.Context, meth, url string, in, out interface{}, opts ...RequestOption) error {
 	bd, err := encodeXML(in)
 	if err != nil {
 		return err
 	}
+	var do DoFunc
+	if out != nil {
+		do = decodeBody(out)
+	}
+	return c.doBody(ctx, meth, url, bd, do, opts...)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+func (c *Client) doBody(ctx context.Context, meth, url string, bd body, do DoFunc, opts ...RequestOption) error {
+	cl, err := c.makeCall(url, opts)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := cl.context(ctx)
 	defer cancel()
 
-	res, err := c.execute(ctx, meth, url, bd)
+	res, err := c.execute(ctx, meth, cl.url, bd, cl)
 	if err != nil {
 		return err
 	}
-	var do DoFunc
-	if out != nil {
-		do = decodeBody(out)
-	}
 	return c.decodeResponse(res, do)
 }
 
-func (c *Client) doFollow(url string, rel RelType, do DoFunc) error {
+func (c *Client) doFollow(ctx context.Context, url string, rel RelType, do DoFunc, opts ...RequestOption) error {
+	cl, err := c.makeCall(url, opts)
+	if err != nil {
+		return err
+	}
 	var (
 		list []string
 		seen = make(map[string]struct{})
 	)
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	ctx, cancel := cl.context(ctx)
 	defer cancel()
 
-	list = append(list, url)
+	list = append(list, cl.url)
 	for len(list) > 0 {
-		res, err := c.execute(ctx, http.MethodGet, list[0], emptyBody())
+		res, err := c.execute(ctx, http.MethodGet, list[0], emptyBody(), cl)
 		if err != nil {
 			return err
 		}
@@ -381,26 +604,33 @@ func (c *Client) doFollow(url string, rel RelType, do DoFunc) error {
 	return nil
 }
 
-func (c *Client) execute(ctx context.Context, meth, url string, bd body) (*http.Response, error) {
-	req, err := c.prepare(ctx, meth, url, bd)
+func (c *Client) execute(ctx context.Context, meth, url string, bd body, cl call) (*http.Response, error) {
+	req, err := c.prepare(ctx, meth, url, bd, cl)
 	if err != nil {
 		return nil, err
 	}
-	// if meth == http.MethodGet {
-	// 	var res *http.Response
-	// 	err = try.Try(c.retry, func(_ int) error {
-	// 		r, err := c.client.Do(req)
-	// 		if err == nil {
-	// 			res = r
-	// 		}
-	// 		return err
-	// 	})
-	// 	return res, err
-	// }
-	return c.client.Do(req)
-}
-
-func (c *Client) prepare(ctx context.Context, meth, url string, bd body) (*http.Request, error) {
+	return c.send(req, cl)
+}
+
+func (c *Client) send(req *http.Request, cl call) (*http.Response, error) {
+	now := time.Now()
+	res, n, err := c.retry.do(req, c.client.Do)
+	if cl.res != nil {
+		cl.res.update(res, time.Since(now), n)
+	}
+	if err == nil && c.Cache != nil {
+		c.Cache.Invalidate(req, res)
+	}
+	return res, err
+}
+
+func (c *Client) prepare(ctx context.Context, meth, url string, bd body, cl call) (*http.Request, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if cl.res != nil {
+		ctx = context.WithValue(ctx, captureKey{}, cl.res)
+	}
 	req, err := http.NewRequestWithContext(ctx, meth, url, bd.Reader)
 	if err != nil {
 		return nil, err
@@ -419,15 +649,32 @@ func (c *Client) prepare(ctx context.Context, meth, url string, bd body) (*http.
 	for k, v := range c.headers {
 		req.Header[k] = v
 	}
-	if c.user != "" {
-		req.SetBasicAuth(c.user, c.pass)
+	if cl.user != "" {
+		req.SetBasicAuth(cl.user, cl.pass)
 	}
-	if c.transform != nil {
-		c.transform(req)
+	for k, v := range cl.headers {
+		req.Header[k] = v
+	}
+	for _, fn := range cl.transform {
+		if err := fn(req); err != nil {
+			return nil, err
+		}
 	}
 	return req, nil
 }
 
+func queryRequest(req *http.Request, query, operation string, vars Values) (*http.Request, error) {
+	key, err := queryKey(query, operation, vars)
+	if err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.Method = http.MethodGet
+	r.Body, r.GetBody, r.ContentLength = nil, nil, 0
+	r.URL.Path = path.Join(r.URL.Path, key)
+	return r, nil
+}
+
 func (c *Client) decodeResponse(res *http.Response, do DoFunc) error {
 	defer res.Body.Close()
 
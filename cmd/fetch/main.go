@@ -13,6 +13,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/midbel/fetch"
 )
 
 type Header struct {
@@ -62,6 +64,59 @@ func (s *HeaderSet) Modify(req *http.Request) {
 	}
 }
 
+type Field struct {
+	Name  string
+	Value string
+	Type  string
+	File  bool
+}
+
+func (f *Field) Set(str string) error {
+	x := strings.Index(str, "=")
+	if x <= 0 {
+		return fmt.Errorf("%s: invalid form field", str)
+	}
+	f.Name, f.Value = str[:x], str[x+1:]
+	if f.File = strings.HasPrefix(f.Value, "@"); f.File {
+		f.Value = f.Value[1:]
+		if x := strings.Index(f.Value, ";type="); x > 0 {
+			f.Value, f.Type = f.Value[:x], f.Value[x+6:]
+		}
+	}
+	return nil
+}
+
+func (f *Field) String() string {
+	return fmt.Sprintf("%s=%s", f.Name, f.Value)
+}
+
+type FieldSet []Field
+
+func (s *FieldSet) Set(str string) error {
+	var f Field
+	if err := f.Set(str); err != nil {
+		return err
+	}
+	*s = append(*s, f)
+	return nil
+}
+
+func (s *FieldSet) String() string {
+	return "form field"
+}
+
+func (s *FieldSet) Multipart() *fetch.Multipart {
+	m := fetch.NewMultipart()
+	for _, f := range *s {
+		if f.File {
+			m.Path(f.Name, f.Value, f.Type)
+		} else {
+			m.Field(f.Name, f.Value)
+		}
+	}
+	return m
+}
+
 type Builder struct {
 	Config   string
 	Body     string
@@ -73,11 +128,20 @@ type Builder struct {
 	Timeout  time.Duration
 	Insecure bool
 	Set      HeaderSet
+	Form     FieldSet
 }
 
 func (b Builder) Build(url string) (*http.Request, error) {
-	var r io.Reader
-	if strings.HasPrefix(b.Body, "@") {
+	var (
+		r  io.Reader
+		ct string
+	)
+	if len(b.Form) > 0 && b.Body != "" {
+		return nil, fmt.Errorf("body and form fields can not be used together")
+	}
+	if len(b.Form) > 0 {
+		r, ct = b.Form.Multipart().Open()
+	} else if strings.HasPrefix(b.Body, "@") {
 		buf, err := os.ReadFile(b.Body[1:])
 		if err != nil {
 			return nil, err
@@ -86,7 +150,7 @@ func (b Builder) Build(url string) (*http.Request, error) {
 	} else {
 		r = strings.NewReader(b.Body)
 	}
-	if b.Body != "" && strings.ToUpper(b.Meth) == http.MethodGet {
+	if (b.Body != "" || len(b.Form) > 0) && strings.ToUpper(b.Meth) == http.MethodGet {
 		b.Meth = http.MethodPost
 	}
 
@@ -94,6 +158,9 @@ func (b Builder) Build(url string) (*http.Request, error) {
 	if err != nil {
 		return nil, err
 	}
+	if ct != "" {
+		req.Header.Set("content-type", ct)
+	}
 	if b.User != "" {
 		req.SetBasicAuth(b.User, b.Pass)
 	}
@@ -113,8 +180,9 @@ func (b Builder) Do(url string) (*http.Response, error) {
 			RootCAs:            pool,
 		}
 	)
+	retry := fetch.DefaultRetry(b.Retry)
 	c := http.Client{
-		Transport: createTransport(b.Timeout, &cfg),
+		Transport: retry.Transport(createTransport(b.Timeout, &cfg)),
 		Timeout:   b.Timeout,
 	}
 	return c.Do(req)
@@ -182,8 +250,9 @@ func main() {
 	flag.StringVar(&builder.Config, "K", "", "use options specified in configuration file")
 	flag.BoolVar(&builder.Insecure, "k", false, "ignore invalid certificates")
 	flag.StringVar(&builder.CADir, "c", "", "path to CA certificate(s)")
-	flag.IntVar(&builder.Retry, "r", 0, "retry")
+	flag.IntVar(&builder.Retry, "r", 0, "retry count for idempotent requests")
 	flag.Var(&builder.Set, "H", "custom http headers")
+	flag.Var(&builder.Form, "F", "multipart form field (name=value or name=@file)")
 	flag.BoolVar(&writer.Verb, "v", false, "verbose")
 	flag.BoolVar(&writer.Tee, "t", false, "write output to file and stdout")
 	flag.BoolVar(&writer.Same, "o", false, "write output to file")
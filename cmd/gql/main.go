@@ -17,13 +17,13 @@ func main() {
 	}
 	query, err := os.ReadFile(flag.Arg(1))
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "unable to read query from file %s", flag.Arg(1))
+		fmt.Fprintf(os.Stderr, "unable to read query from file %s\n", flag.Arg(1))
 		return
 	}
 	err = fetch.QueryWith(flag.Arg(0), string(query), nil, func(_ string, r io.Reader) error {
 		_, err := io.Copy(os.Stdout, r)
 		return err
-	})
+	}, fetch.Partial())
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -2,6 +2,7 @@ package fetch
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
@@ -32,44 +33,148 @@ func RegisterDecodeFunc(ct string, fn DecodeFunc) {
 	decoders[ct] = fn
 }
 
-func Get(url string, out interface{}) error {
-	return DefaultClient.Get(url, out)
+func Get(url string, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.Get(url, out, opts...)
 }
 
-func GetWith(url string, do DoFunc) error {
-	return DefaultClient.GetWith(url, do)
+func GetContext(ctx context.Context, url string, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.GetContext(ctx, url, out, opts...)
 }
 
-func Delete(url string, out interface{}) error {
-	return DefaultClient.Delete(url, out)
+func GetWith(url string, do DoFunc, opts ...RequestOption) error {
+	return DefaultClient.GetWith(url, do, opts...)
 }
 
-func DeleteJSON(url string, in, out interface{}) error {
-	return DefaultClient.DeleteJSON(url, in, out)
+func GetWithContext(ctx context.Context, url string, do DoFunc, opts ...RequestOption) error {
+	return DefaultClient.GetWithContext(ctx, url, do, opts...)
 }
 
-func Follow(url string, rel RelType, do DoFunc) error {
-	return DefaultClient.Follow(url, rel, do)
+func Delete(url string, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.Delete(url, out, opts...)
 }
 
-func Query(url, query string, vars Values, out interface{}) error {
-	return DefaultClient.Query(url, query, vars, out)
+func DeleteContext(ctx context.Context, url string, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.DeleteContext(ctx, url, out, opts...)
 }
 
-func QueryWith(url, query string, vars Values, do DoFunc) error {
-	return DefaultClient.QueryWith(url, query, vars, do)
+func DeleteJSON(url string, in, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.DeleteJSON(url, in, out, opts...)
 }
 
-func PostJSON(url string, in, out interface{}) error {
-	return DefaultClient.PostJSON(url, in, out)
+func DeleteJSONContext(ctx context.Context, url string, in, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.DeleteJSONContext(ctx, url, in, out, opts...)
 }
 
-func PutJSON(url string, in, out interface{}) error {
-	return DefaultClient.PutJSON(url, in, out)
+func Head(url string, opts ...RequestOption) (http.Header, int, error) {
+	return DefaultClient.Head(url, opts...)
 }
 
-func PatchJSON(url string, in, out interface{}) error {
-	return DefaultClient.PatchJSON(url, in, out)
+func HeadContext(ctx context.Context, url string, opts ...RequestOption) (http.Header, int, error) {
+	return DefaultClient.HeadContext(ctx, url, opts...)
+}
+
+func Options(url string, opts ...RequestOption) (http.Header, int, error) {
+	return DefaultClient.Options(url, opts...)
+}
+
+func OptionsContext(ctx context.Context, url string, opts ...RequestOption) (http.Header, int, error) {
+	return DefaultClient.OptionsContext(ctx, url, opts...)
+}
+
+func Follow(url string, rel RelType, do DoFunc, opts ...RequestOption) error {
+	return DefaultClient.Follow(url, rel, do, opts...)
+}
+
+func FollowContext(ctx context.Context, url string, rel RelType, do DoFunc, opts ...RequestOption) error {
+	return DefaultClient.FollowContext(ctx, url, rel, do, opts...)
+}
+
+func Query(url, query string, vars Values, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.Query(url, query, vars, out, opts...)
+}
+
+func QueryContext(ctx context.Context, url, query string, vars Values, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.QueryContext(ctx, url, query, vars, out, opts...)
+}
+
+func QueryWith(url, query string, vars Values, do DoFunc, opts ...RequestOption) error {
+	return DefaultClient.QueryWith(url, query, vars, do, opts...)
+}
+
+func QueryWithContext(ctx context.Context, url, query string, vars Values, do DoFunc, opts ...RequestOption) error {
+	return DefaultClient.QueryWithContext(ctx, url, query, vars, do, opts...)
+}
+
+func PostJSON(url string, in, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.PostJSON(url, in, out, opts...)
+}
+
+func PostJSONContext(ctx context.Context, url string, in, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.PostJSONContext(ctx, url, in, out, opts...)
+}
+
+func PostWithBody(url string, r io.Reader, ct string, do DoFunc, opts ...RequestOption) error {
+	return DefaultClient.PostWithBody(url, r, ct, do, opts...)
+}
+
+func PostWithBodyContext(ctx context.Context, url string, r io.Reader, ct string, do DoFunc, opts ...RequestOption) error {
+	return DefaultClient.PostWithBodyContext(ctx, url, r, ct, do, opts...)
+}
+
+func PostMultipart(url string, m *Multipart, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.PostMultipart(url, m, out, opts...)
+}
+
+func PostMultipartContext(ctx context.Context, url string, m *Multipart, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.PostMultipartContext(ctx, url, m, out, opts...)
+}
+
+func PostForm(url string, in, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.PostForm(url, in, out, opts...)
+}
+
+func PostFormContext(ctx context.Context, url string, in, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.PostFormContext(ctx, url, in, out, opts...)
+}
+
+func PutJSON(url string, in, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.PutJSON(url, in, out, opts...)
+}
+
+func PutJSONContext(ctx context.Context, url string, in, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.PutJSONContext(ctx, url, in, out, opts...)
+}
+
+func PutWithBody(url string, r io.Reader, ct string, do DoFunc, opts ...RequestOption) error {
+	return DefaultClient.PutWithBody(url, r, ct, do, opts...)
+}
+
+func PutWithBodyContext(ctx context.Context, url string, r io.Reader, ct string, do DoFunc, opts ...RequestOption) error {
+	return DefaultClient.PutWithBodyContext(ctx, url, r, ct, do, opts...)
+}
+
+func PutForm(url string, in, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.PutForm(url, in, out, opts...)
+}
+
+func PutFormContext(ctx context.Context, url string, in, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.PutFormContext(ctx, url, in, out, opts...)
+}
+
+func PatchJSON(url string, in, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.PatchJSON(url, in, out, opts...)
+}
+
+func PatchJSONContext(ctx context.Context, url string, in, out interface{}, opts ...RequestOption) error {
+	return DefaultClient.PatchJSONContext(ctx, url, in, out, opts...)
+}
+
+func PatchWithBody(url string, r io.Reader, ct string, do DoFunc, opts ...RequestOption) error {
+	return DefaultClient.PatchWithBody(url, r, ct, do, opts...)
+}
+
+func PatchWithBodyContext(ctx context.Context, url string, r io.Reader, ct string, do DoFunc, opts ...RequestOption) error {
+	return DefaultClient.PatchWithBodyContext(ctx, url, r, ct, do, opts...)
 }
 
 type RelType byte
@@ -175,16 +280,3 @@ func makeBody(ct string, r io.Reader) body {
 		Reader: r,
 	}
 }
-
-func makeQuery(query string, vars Values) interface{} {
-	q := struct {
-		Query string                 `json:"query"`
-		Vars  map[string]interface{} `json:"variables,omitempty"`
-	}{
-		Query: query,
-	}
-	if len(vars) > 0 {
-		q.Vars = vars
-	}
-	return q
-}
@@ -0,0 +1,277 @@
+package fetch
+
+import (
+	"encoding/json"
+	"io"
+	urllib "net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const fileFlush = time.Second
+
+type filecache struct {
+	dir string
+
+	mu    sync.Mutex
+	items map[string]*item
+	dirty bool
+
+	flushing sync.Mutex
+	done     chan struct{}
+	once     sync.Once
+}
+
+func FileCache(dir string, size int, ttl time.Duration, options ...CacheOption) Cache {
+	c := filecache{
+		dir:   filepath.Join(dir, cacheFile),
+		items: make(map[string]*item),
+		done:  make(chan struct{}),
+	}
+	c.load()
+	if size > 0 {
+		options = append([]CacheOption{WithMaxEntries(size)}, options...)
+	}
+	go c.flusher(fileFlush)
+	return newCache(&c, ttl, options)
+}
+
+func (c *filecache) Close() error {
+	c.once.Do(func() {
+		close(c.done)
+	})
+	return c.flush()
+}
+
+func (c *filecache) lookup(key string) (*meta, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i, ok := c.items[key]
+	if !ok {
+		return nil, errMissing
+	}
+	m := i.meta.clone()
+	return &m, nil
+}
+
+func (c *filecache) open(key string) (io.ReadCloser, error) {
+	c.mu.Lock()
+	i, ok := c.items[key]
+	c.mu.Unlock()
+	if !ok {
+		return nil, errMissing
+	}
+	return os.Open(c.path(i.File))
+}
+
+func (c *filecache) create(key string, m *meta) (writer, error) {
+	file, err := c.prepare(key, m.URL)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(filepath.Dir(c.path(file)), tmpPrefix+"*")
+	if err != nil {
+		return nil, err
+	}
+	w := filewriter{
+		File:  f,
+		cache: c,
+		key:   key,
+		file:  file,
+		meta:  m,
+	}
+	return &w, nil
+}
+
+func (c *filecache) update(key string, m *meta) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i, ok := c.items[key]
+	if !ok {
+		return errMissing
+	}
+	i.meta = m.clone()
+	c.dirty = true
+	return nil
+}
+
+func (c *filecache) remove(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	delete(c.items, key)
+	os.Remove(c.path(i.File))
+	c.dirty = true
+	return nil
+}
+
+func (c *filecache) prepare(key, url string) (string, error) {
+	var dir string
+	if loc, err := urllib.Parse(url); err == nil {
+		dir = loc.Hostname()
+	}
+	file := filepath.Join(dir, key)
+	return file, os.MkdirAll(filepath.Dir(c.path(file)), 0755)
+}
+
+func (c *filecache) walk(fn func(*meta) error) error {
+	c.mu.Lock()
+	ms := make([]meta, 0, len(c.items))
+	for _, i := range c.items {
+		ms = append(ms, i.meta.clone())
+	}
+	c.mu.Unlock()
+
+	for i := range ms {
+		if err := fn(&ms[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *filecache) path(file string) string {
+	return filepath.Join(c.dir, file)
+}
+
+func (c *filecache) load() {
+	buf, err := os.ReadFile(c.path(fileIndex))
+	if err == nil {
+		json.Unmarshal(buf, &c.items)
+	}
+	if c.items == nil {
+		c.items = make(map[string]*item)
+	}
+	files := make(map[string]struct{})
+	for k, i := range c.items {
+		s, err := os.Stat(c.path(i.File))
+		if err != nil || s.Size() != i.Size {
+			delete(c.items, k)
+			os.Remove(c.path(i.File))
+			continue
+		}
+		i.Key = k
+		files[c.path(i.File)] = struct{}{}
+	}
+	filepath.Walk(c.dir, func(file string, i os.FileInfo, err error) error {
+		if err != nil || i.IsDir() || file == c.path(fileIndex) {
+			return nil
+		}
+		if _, ok := files[file]; !ok || strings.HasPrefix(i.Name(), tmpPrefix) {
+			os.Remove(file)
+		}
+		return nil
+	})
+}
+
+func (c *filecache) flusher(every time.Duration) {
+	tick := time.NewTicker(every)
+	defer tick.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-tick.C:
+			c.flush()
+		}
+	}
+}
+
+func (c *filecache) flush() error {
+	c.flushing.Lock()
+	defer c.flushing.Unlock()
+
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return nil
+	}
+	items := make(map[string]item, len(c.items))
+	for k, i := range c.items {
+		items[k] = *i
+	}
+	c.dirty = false
+	c.mu.Unlock()
+
+	err := c.save(items)
+	if err != nil {
+		c.mu.Lock()
+		c.dirty = true
+		c.mu.Unlock()
+	}
+	return err
+}
+
+func (c *filecache) save(items map[string]item) error {
+	buf, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(c.dir, tmpPrefix+"*")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(buf); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path(fileIndex))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+const tmpPrefix = ".tmp-"
+
+type item struct {
+	meta
+	File string `json:"file"`
+}
+
+type filewriter struct {
+	*os.File
+	cache *filecache
+	key   string
+	file  string
+	meta  *meta
+}
+
+func (w *filewriter) commit() error {
+	if err := w.Close(); err != nil {
+		os.Remove(w.Name())
+		return err
+	}
+	if err := os.Rename(w.Name(), w.cache.path(w.file)); err != nil {
+		os.Remove(w.Name())
+		return err
+	}
+	w.cache.mu.Lock()
+	defer w.cache.mu.Unlock()
+	w.cache.items[w.key] = &item{
+		meta: w.meta.clone(),
+		File: w.file,
+	}
+	w.cache.dirty = true
+	return nil
+}
+
+func (w *filewriter) abort() error {
+	w.Close()
+	return os.Remove(w.Name())
+}
@@ -0,0 +1,152 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+func WithCoalescing() Option {
+	return func(c *Client) {
+		c.flight = &flight{
+			calls: make(map[string]*flightCall),
+		}
+	}
+}
+
+type flight struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	waiters int
+	orphan  bool
+	decided bool
+	shared  bool
+
+	called bool
+	ctype  string
+	body   []byte
+	err    error
+}
+
+func (f *flight) do(ctx context.Context, key string, do DoFunc, fn func(context.Context, DoFunc) error) error {
+	f.mu.Lock()
+	x, ok := f.calls[key]
+	if !ok {
+		x = &flightCall{
+			done: make(chan struct{}),
+		}
+		x.ctx, x.cancel = context.WithCancel(detached{ctx})
+		f.calls[key] = x
+		go f.run(key, x, do, fn)
+	}
+	x.waiters++
+	f.mu.Unlock()
+
+	select {
+	case <-x.done:
+	case <-ctx.Done():
+		if f.leave(key, x, !ok) {
+			<-x.done
+		}
+		return ctx.Err()
+	}
+	if !ok && !x.shared {
+		return x.err
+	}
+	return x.result(do)
+}
+
+func (f *flight) run(key string, x *flightCall, do DoFunc, fn func(context.Context, DoFunc) error) {
+	x.err = fn(x.ctx, func(ct string, r io.Reader) error {
+		f.mu.Lock()
+		x.decided, x.shared = true, x.orphan || x.waiters > 1
+		if !x.shared {
+			f.forget(key, x)
+		}
+		f.mu.Unlock()
+
+		if !x.shared {
+			if do == nil {
+				return nil
+			}
+			return do(ct, r)
+		}
+		body, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		x.called, x.ctype, x.body = true, ct, body
+		return nil
+	})
+
+	f.mu.Lock()
+	f.forget(key, x)
+	f.mu.Unlock()
+
+	x.cancel()
+	close(x.done)
+}
+
+func (f *flight) leave(key string, x *flightCall, leader bool) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	x.waiters--
+	if leader {
+		x.orphan = true
+	}
+	if x.waiters == 0 {
+		f.forget(key, x)
+		x.cancel()
+	}
+	return leader && x.decided && !x.shared
+}
+
+func (f *flight) forget(key string, x *flightCall) {
+	if f.calls[key] == x {
+		delete(f.calls, key)
+	}
+}
+
+func (x *flightCall) result(do DoFunc) error {
+	if x.err != nil || !x.called || do == nil {
+		return x.err
+	}
+	return do(x.ctype, bytes.NewReader(x.body))
+}
+
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
+
+func flightKey(req *http.Request) string {
+	var buf bytes.Buffer
+	buf.WriteString(req.Method)
+	buf.WriteString(" ")
+	buf.WriteString(req.URL.String())
+	buf.WriteString("\n")
+	req.Header.Write(&buf)
+	return hashKey(buf.String())
+}
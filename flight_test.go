@@ -0,0 +1,194 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightShared(t *testing.T) {
+	var (
+		f       = newFlight()
+		calls   int32
+		release = make(chan struct{})
+	)
+	fetch := func(_ context.Context, do DoFunc) error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return do("text/plain", strings.NewReader("shared"))
+	}
+
+	const callers = 5
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f.do(context.Background(), "key", expectBody(t, "shared"), fetch); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	waitWaiters(t, f, "key", callers)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("fetch called %d times, want 1", n)
+	}
+}
+
+func TestFlightLeaderCancelled(t *testing.T) {
+	var (
+		f       = newFlight()
+		release = make(chan struct{})
+		joined  = make(chan struct{})
+	)
+	fetch := func(ctx context.Context, do DoFunc) error {
+		<-release
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return do("text/plain", strings.NewReader("shared"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		leader <- f.do(ctx, "key", expectBody(t, "shared"), fetch)
+	}()
+	waitWaiters(t, f, "key", 1)
+
+	follower := make(chan error, 1)
+	go func() {
+		close(joined)
+		follower <- f.do(context.Background(), "key", expectBody(t, "shared"), fetch)
+	}()
+	<-joined
+	waitWaiters(t, f, "key", 2)
+
+	cancel()
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader: got %v, want %v", err, context.Canceled)
+	}
+	close(release)
+	if err := <-follower; err != nil {
+		t.Errorf("follower: %v", err)
+	}
+}
+
+func TestFlightOrphaned(t *testing.T) {
+	var (
+		f         = newFlight()
+		cancelled = make(chan struct{})
+	)
+	fetch := func(ctx context.Context, _ DoFunc) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- f.do(ctx, "key", nil, fetch)
+	}()
+	waitWaiters(t, f, "key", 1)
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("shared fetch not cancelled once every caller left")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.calls) != 0 {
+		t.Errorf("%d calls still pending, want none", len(f.calls))
+	}
+}
+
+func TestFlightLateCaller(t *testing.T) {
+	var (
+		f     = newFlight()
+		calls int32
+	)
+	fetch := func(_ context.Context, do DoFunc) error {
+		atomic.AddInt32(&calls, 1)
+		return do("text/plain", strings.NewReader("body"))
+	}
+	for i := 0; i < 2; i++ {
+		if err := f.do(context.Background(), "key", expectBody(t, "body"), fetch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("fetch called %d times, want 2", n)
+	}
+}
+
+func TestFlightContextValues(t *testing.T) {
+	type key struct{}
+
+	var (
+		f   = newFlight()
+		got interface{}
+	)
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), key{}, "trace"), time.Second)
+	defer cancel()
+	err := f.do(ctx, "key", nil, func(ctx context.Context, _ DoFunc) error {
+		got = ctx.Value(key{})
+		if _, ok := ctx.Deadline(); ok {
+			return errors.New("deadline inherited from the caller")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "trace" {
+		t.Errorf("got value %v, want trace", got)
+	}
+}
+
+func newFlight() *flight {
+	return &flight{
+		calls: make(map[string]*flightCall),
+	}
+}
+
+func waitWaiters(t *testing.T, f *flight, key string, n int) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		f.mu.Lock()
+		x, ok := f.calls[key]
+		ready := ok && x.waiters >= n
+		f.mu.Unlock()
+		if ready {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("%d waiters never joined %s", n, key)
+}
+
+func expectBody(t *testing.T, want string) DoFunc {
+	return func(_ string, r io.Reader) error {
+		buf, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if string(buf) != want {
+			t.Errorf("got %q, want %q", buf, want)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,227 @@
+package fetch
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	urllib "net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ctform = "application/x-www-form-urlencoded"
+
+func BuildURL(url string, params interface{}) (string, error) {
+	loc, err := urllib.Parse(url)
+	if err != nil {
+		return "", err
+	}
+	vs, err := EncodeValues(params)
+	if err != nil {
+		return "", err
+	}
+	if len(vs) == 0 {
+		return loc.String(), nil
+	}
+	qs := loc.Query()
+	for k, v := range vs {
+		qs[k] = append(qs[k], v...)
+	}
+	loc.RawQuery = qs.Encode()
+	return loc.String(), nil
+}
+
+func EncodeValues(in interface{}) (urllib.Values, error) {
+	vs := make(urllib.Values)
+	switch in := in.(type) {
+	case nil:
+		return vs, nil
+	case urllib.Values:
+		for k, v := range in {
+			vs[k] = append(vs[k], v...)
+		}
+		return vs, nil
+	}
+	v := reflect.ValueOf(in)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return vs, nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map:
+	default:
+		return nil, fmt.Errorf("%s: %w", v.Type(), errFormType)
+	}
+	return vs, encodeValue(vs, "", v)
+}
+
+func encodeForm(in interface{}) (body, error) {
+	vs, err := EncodeValues(in)
+	if err != nil {
+		return emptyBody(), err
+	}
+	return makeBody(ctform, strings.NewReader(vs.Encode())), nil
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	textType    = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	stringType  = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	errFormType = errors.New("unsupported form value")
+)
+
+func encodeValue(vs urllib.Values, name string, v reflect.Value) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if str, ok, err := formatText(v); ok {
+		if err == nil {
+			vs.Add(name, str)
+		}
+		return err
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return encodeStruct(vs, name, v)
+	case reflect.Map:
+		return encodeMap(vs, name, v)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			vs.Add(name, string(v.Bytes()))
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeValue(vs, name, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	str, err := formatValue(v)
+	if err == nil {
+		vs.Add(name, str)
+	}
+	return err
+}
+
+func encodeStruct(vs urllib.Values, prefix string, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		var (
+			f  = t.Field(i)
+			fv = v.Field(i)
+		)
+		embed := f.Anonymous && indirectType(f.Type).Kind() == reflect.Struct
+		if f.PkgPath != "" && !embed {
+			continue
+		}
+		name, omit := parseFormTag(f)
+		if name == "-" {
+			continue
+		}
+		if omit && fv.IsZero() {
+			continue
+		}
+		if embed && name == "" {
+			if err := encodeValue(vs, prefix, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if err := encodeValue(vs, joinName(prefix, name), fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeMap(vs urllib.Values, prefix string, v reflect.Value) error {
+	if v.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("%s: %w", v.Type(), errFormType)
+	}
+	iter := v.MapRange()
+	for iter.Next() {
+		name := joinName(prefix, iter.Key().String())
+		if err := encodeValue(vs, name, iter.Value()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatText(v reflect.Value) (string, bool, error) {
+	if !v.CanInterface() {
+		return "", false, nil
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339), true, nil
+	}
+	if v.CanAddr() && !v.Type().Implements(textType) && v.Addr().Type().Implements(textType) {
+		v = v.Addr()
+	}
+	if v.Type().Implements(textType) {
+		buf, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(buf), true, err
+	}
+	if v.Type().Implements(stringType) {
+		return v.Interface().(fmt.Stringer).String(), true, nil
+	}
+	return "", false, nil
+}
+
+func formatValue(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("%s: %w", v.Type(), errFormType)
+	}
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func parseFormTag(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("form")
+	if tag == "" {
+		return "", false
+	}
+	parts := strings.Split(tag, ",")
+	for _, p := range parts[1:] {
+		if p == "omitempty" {
+			return parts[0], true
+		}
+	}
+	return parts[0], false
+}
+
+func joinName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -3,6 +3,5 @@ module github.com/midbel/fetch
 go 1.16
 
 require (
-	github.com/midbel/xxh v1.1.0
 	go.etcd.io/bbolt v1.3.6
 )
@@ -0,0 +1,225 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Locations  []GraphQLLocation      `json:"locations,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+func (e GraphQLError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+	parts := make([]string, len(e.Path))
+	for i := range e.Path {
+		parts[i] = fmt.Sprint(e.Path[i])
+	}
+	return fmt.Sprintf("%s: %s", strings.Join(parts, "."), e.Message)
+}
+
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	switch len(e) {
+	case 0:
+		return "no error"
+	case 1:
+		return e[0].Error()
+	default:
+		return fmt.Sprintf("%s (and %d more error(s))", e[0].Error(), len(e)-1)
+	}
+}
+
+func decodeQuery(do DoFunc, partial bool) DoFunc {
+	return func(ct string, r io.Reader) error {
+		buf, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		var res struct {
+			Errors GraphQLErrors `json:"errors"`
+		}
+		json.Unmarshal(buf, &res)
+		if len(res.Errors) > 0 && !partial {
+			return res.Errors
+		}
+		if do != nil {
+			if err := do(ct, bytes.NewReader(buf)); err != nil {
+				return err
+			}
+		}
+		if len(res.Errors) > 0 {
+			return res.Errors
+		}
+		return nil
+	}
+}
+
+func makeQuery(query, operation string, vars Values) interface{} {
+	q := struct {
+		Query     string                 `json:"query"`
+		Operation string                 `json:"operationName,omitempty"`
+		Vars      map[string]interface{} `json:"variables,omitempty"`
+	}{
+		Query:     query,
+		Operation: operation,
+	}
+	if len(vars) > 0 {
+		q.Vars = vars
+	}
+	return q
+}
+
+func queryKey(query, operation string, vars Values) (string, error) {
+	var str strings.Builder
+	str.WriteString(normalizeQuery(query))
+	str.WriteString("\n")
+	str.WriteString(operation)
+	str.WriteString("\n")
+	if len(vars) > 0 {
+		buf, err := json.Marshal(vars)
+		if err != nil {
+			return "", err
+		}
+		str.Write(buf)
+	}
+	return hashKey(str.String()), nil
+}
+
+func isCacheableQuery(query, operation string) bool {
+	var (
+		doc   = normalizeQuery(query)
+		depth int
+		kind  string
+		first = true
+	)
+	for i := 0; i < len(doc); i++ {
+		switch doc[i] {
+		case '{':
+			if depth == 0 && kind == "" {
+				if operation == "" && first {
+					return true
+				}
+				kind, first = "query", false
+			}
+			depth++
+			continue
+		case '}':
+			depth--
+			if depth == 0 {
+				kind = ""
+			}
+			continue
+		case '"':
+			i = skipString(doc, i)
+			continue
+		}
+		if depth > 0 || kind != "" || !isNameChar(doc[i]) {
+			continue
+		}
+		j := i
+		for j < len(doc) && isNameChar(doc[j]) {
+			j++
+		}
+		kind = doc[i:j]
+		for j < len(doc) && doc[j] == ' ' {
+			j++
+		}
+		var name string
+		if k := j; k < len(doc) && isNameChar(doc[k]) {
+			for k < len(doc) && isNameChar(doc[k]) {
+				k++
+			}
+			name = doc[j:k]
+			j = k
+		}
+		i = j - 1
+		if kind == "fragment" {
+			continue
+		}
+		if (operation == "" && first) || name == operation {
+			return kind == "query"
+		}
+		first = false
+	}
+	return operation == "" && first
+}
+
+func normalizeQuery(query string) string {
+	var (
+		str   strings.Builder
+		space bool
+	)
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			space = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			space = true
+		case c == '"':
+			j := skipString(query, i)
+			if space && str.Len() > 0 && !isPunct(lastByte(str.String())) {
+				str.WriteByte(' ')
+			}
+			str.WriteString(query[i : j+1])
+			i, space = j, false
+		default:
+			if space && str.Len() > 0 && !isPunct(c) && !isPunct(lastByte(str.String())) {
+				str.WriteByte(' ')
+			}
+			str.WriteByte(c)
+			space = false
+		}
+	}
+	return str.String()
+}
+
+func skipString(str string, i int) int {
+	if strings.HasPrefix(str[i:], `"""`) {
+		if x := strings.Index(str[i+3:], `"""`); x >= 0 {
+			return i + 3 + x + 2
+		}
+		return len(str) - 1
+	}
+	for j := i + 1; j < len(str); j++ {
+		switch str[j] {
+		case '\\':
+			j++
+		case '"':
+			return j
+		}
+	}
+	return len(str) - 1
+}
+
+func lastByte(str string) byte {
+	if str == "" {
+		return 0
+	}
+	return str[len(str)-1]
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("!$&()[]{}:=@|", c) >= 0
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,113 @@
+package fetch
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+type lru struct {
+	maxBytes int64
+	maxItems int
+
+	mu    sync.RWMutex
+	list  *list.List
+	items map[string]*list.Element
+	bytes int64
+}
+
+type lruEntry struct {
+	key  string
+	size int64
+	used int32
+}
+
+func newLRU(maxBytes int64, maxItems int) *lru {
+	return &lru{
+		maxBytes: maxBytes,
+		maxItems: maxItems,
+		list:     list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (u *lru) load(s store) []string {
+	var ms []*meta
+	s.walk(func(m *meta) error {
+		ms = append(ms, m)
+		return nil
+	})
+	sort.Slice(ms, func(i, j int) bool {
+		return ms[i].Stored.Before(ms[j].Stored)
+	})
+	var keys []string
+	for _, m := range ms {
+		keys = append(keys, u.add(m.Key, m.weight())...)
+	}
+	return keys
+}
+
+func (u *lru) touch(key string) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	if e, ok := u.items[key]; ok {
+		atomic.StoreInt32(&e.Value.(*lruEntry).used, 1)
+	}
+}
+
+func (u *lru) add(key string, size int64) []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if e, ok := u.items[key]; ok {
+		u.bytes -= e.Value.(*lruEntry).size
+		u.list.Remove(e)
+	}
+	u.items[key] = u.list.PushFront(&lruEntry{
+		key:  key,
+		size: size,
+	})
+	u.bytes += size
+
+	var keys []string
+	for u.exceeds() {
+		e := u.list.Back()
+		if e == nil {
+			break
+		}
+		x := e.Value.(*lruEntry)
+		if atomic.SwapInt32(&x.used, 0) == 1 {
+			u.list.MoveToFront(e)
+			continue
+		}
+		u.list.Remove(e)
+		delete(u.items, x.key)
+		u.bytes -= x.size
+		keys = append(keys, x.key)
+	}
+	return keys
+}
+
+func (u *lru) remove(key string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if e, ok := u.items[key]; ok {
+		u.bytes -= e.Value.(*lruEntry).size
+		u.list.Remove(e)
+		delete(u.items, key)
+	}
+}
+
+func (u *lru) stats() (int, int64) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.list.Len(), u.bytes
+}
+
+func (u *lru) exceeds() bool {
+	if u.maxBytes > 0 && u.bytes > u.maxBytes {
+		return true
+	}
+	return u.maxItems > 0 && u.list.Len() > u.maxItems
+}
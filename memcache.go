@@ -0,0 +1,161 @@
+package fetch
+
+import (
+	"bytes"
+	"hash/fnv"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	memShards = 32
+	memSweep  = time.Minute
+)
+
+type memcache struct {
+	shards [memShards]memshard
+	done   chan struct{}
+	once   sync.Once
+}
+
+type memshard struct {
+	mu    sync.RWMutex
+	items map[string]*memitem
+}
+
+type memitem struct {
+	meta meta
+	data []byte
+}
+
+func MemoryCache(size int64, ttl time.Duration, options ...CacheOption) Cache {
+	c := memcache{
+		done: make(chan struct{}),
+	}
+	for i := range c.shards {
+		c.shards[i].items = make(map[string]*memitem)
+	}
+	if size > 0 {
+		options = append([]CacheOption{WithMaxBytes(size)}, options...)
+	}
+	every := memSweep
+	if ttl > 0 && ttl < every {
+		every = ttl
+	}
+	mc := newCache(&c, ttl, options)
+	go mc.sweep(every, c.done)
+	return mc
+}
+
+func (c *memcache) Close() error {
+	c.once.Do(func() {
+		close(c.done)
+	})
+	return nil
+}
+
+func (c *memcache) lookup(key string) (*meta, error) {
+	s := c.shard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i, ok := s.items[key]
+	if !ok {
+		return nil, errMissing
+	}
+	m := i.meta.clone()
+	return &m, nil
+}
+
+func (c *memcache) open(key string) (io.ReadCloser, error) {
+	s := c.shard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i, ok := s.items[key]
+	if !ok {
+		return nil, errMissing
+	}
+	return byteReader{bytes.NewReader(i.data)}, nil
+}
+
+func (c *memcache) create(key string, m *meta) (writer, error) {
+	w := memwriter{
+		cache: c,
+		key:   key,
+		meta:  m,
+	}
+	return &w, nil
+}
+
+func (c *memcache) update(key string, m *meta) error {
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, ok := s.items[key]
+	if !ok {
+		return errMissing
+	}
+	i.meta = m.clone()
+	return nil
+}
+
+func (c *memcache) remove(key string) error {
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.items, key)
+	return nil
+}
+
+func (c *memcache) walk(fn func(*meta) error) error {
+	var ms []meta
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.RLock()
+		for _, i := range s.items {
+			ms = append(ms, i.meta.clone())
+		}
+		s.mu.RUnlock()
+	}
+	for i := range ms {
+		if err := fn(&ms[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *memcache) shard(key string) *memshard {
+	h := fnv.New64a()
+	io.WriteString(h, key)
+	return &c.shards[h.Sum64()%memShards]
+}
+
+type memwriter struct {
+	bytes.Buffer
+	cache *memcache
+	key   string
+	meta  *meta
+}
+
+func (w *memwriter) commit() error {
+	i := memitem{
+		meta: w.meta.clone(),
+		data: append([]byte(nil), w.Bytes()...),
+	}
+	s := w.cache.shard(w.key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.items[w.key] = &i
+	return nil
+}
+
+func (w *memwriter) abort() error {
+	w.Reset()
+	return nil
+}
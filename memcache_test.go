@@ -0,0 +1,63 @@
+package fetch
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheConcurrent(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("cache-control", "max-age=60")
+		fmt.Fprintf(w, "body of %s", r.URL.Path)
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithMemoryCache(1<<20, time.Minute, WithCacheMode(ModePrivate)))
+	read := func(want string) DoFunc {
+		return func(_ string, r io.Reader) error {
+			buf, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			if string(buf) != want {
+				return fmt.Errorf("got %q, want %q", buf, want)
+			}
+			return nil
+		}
+	}
+	const paths = 10
+	for i := 0; i < paths; i++ {
+		p := fmt.Sprintf("/%d", i)
+		if err := c.GetWith(srv.URL+p, read("body of "+p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p := fmt.Sprintf("/%d", i%paths)
+			if err := c.GetWith(srv.URL+p, read("body of "+p)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&hits); n != paths {
+		t.Errorf("upstream called %d times, want %d", n, paths)
+	}
+	if s := c.Stats(); s.Hits != 50 {
+		t.Errorf("cache hits %d, want 50", s.Hits)
+	}
+}
@@ -0,0 +1,33 @@
+package fetch
+
+import (
+	"net/http"
+)
+
+type RoundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+func WithMiddleware(ms ...Middleware) Option {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, ms...)
+	}
+}
+
+func Retry(p RetryPolicy) Middleware {
+	return p.Transport
+}
+
+func Chain(rt http.RoundTripper, ms ...Middleware) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	for i := len(ms) - 1; i >= 0; i-- {
+		rt = ms[i](rt)
+	}
+	return rt
+}
@@ -0,0 +1,130 @@
+package fetch
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const ctoctet = "application/octet-stream"
+
+type Multipart struct {
+	parts []part
+}
+
+func NewMultipart() *Multipart {
+	return &Multipart{}
+}
+
+func (m *Multipart) Field(name, value string) *Multipart {
+	m.parts = append(m.parts, part{
+		name: name,
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(value)), nil
+		},
+	})
+	return m
+}
+
+func (m *Multipart) File(name, file, ct string, r io.Reader) *Multipart {
+	m.parts = append(m.parts, part{
+		name:  name,
+		file:  file,
+		ctype: fileType(file, ct),
+		open: func() (io.ReadCloser, error) {
+			if rc, ok := r.(io.ReadCloser); ok {
+				return rc, nil
+			}
+			return io.NopCloser(r), nil
+		},
+	})
+	return m
+}
+
+func (m *Multipart) Path(name, file, ct string) *Multipart {
+	m.parts = append(m.parts, part{
+		name:  name,
+		file:  filepath.Base(file),
+		ctype: fileType(file, ct),
+		open: func() (io.ReadCloser, error) {
+			return os.Open(file)
+		},
+	})
+	return m
+}
+
+func (m *Multipart) Open() (io.ReadCloser, string) {
+	var (
+		pr, pw = io.Pipe()
+		mw     = multipart.NewWriter(pw)
+	)
+	go func() {
+		err := m.write(mw)
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr, mw.FormDataContentType()
+}
+
+func (m *Multipart) write(mw *multipart.Writer) error {
+	for _, p := range m.parts {
+		if err := p.write(mw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type part struct {
+	name  string
+	file  string
+	ctype string
+	open  func() (io.ReadCloser, error)
+}
+
+func (p part) write(mw *multipart.Writer) error {
+	r, err := p.open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	disp := fmt.Sprintf("form-data; name=\"%s\"", escapeQuotes(p.name))
+	if p.file != "" {
+		disp = fmt.Sprintf("%s; filename=\"%s\"", disp, escapeQuotes(p.file))
+	}
+	hdr := make(textproto.MIMEHeader)
+	hdr.Set("content-disposition", disp)
+	if p.ctype != "" {
+		hdr.Set("content-type", p.ctype)
+	}
+	w, err := mw.CreatePart(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func fileType(file, ct string) string {
+	if ct != "" {
+		return ct
+	}
+	if ct = mime.TypeByExtension(filepath.Ext(file)); ct != "" {
+		return ct
+	}
+	return ctoctet
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(str string) string {
+	return quoteEscaper.Replace(str)
+}
@@ -1,9 +1,13 @@
 package fetch
 
 import (
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"strings"
 )
 
 const (
@@ -50,3 +54,119 @@ func proxy(w http.ResponseWriter, r *http.Request) {
 	}
 	io.Copy(w, res.Body)
 }
+
+type ProxyFunc func(*http.Request) (*url.URL, error)
+
+func WithProxy(addr string) Option {
+	return func(c *Client) {
+		u, err := parseProxy(addr)
+		if err != nil {
+			c.err = err
+			return
+		}
+		c.proxy = http.ProxyURL(u)
+		if len(c.noproxy) == 0 {
+			c.noproxy = splitNoProxy(envNoProxy())
+		}
+	}
+}
+
+func WithProxyFunc(fn ProxyFunc) Option {
+	return func(c *Client) {
+		c.proxy = fn
+	}
+}
+
+func WithEnvProxy() Option {
+	return func(c *Client) {
+		c.proxy = http.ProxyFromEnvironment
+	}
+}
+
+func WithNoProxy(hosts ...string) Option {
+	return func(c *Client) {
+		for _, h := range hosts {
+			c.noproxy = append(c.noproxy, splitNoProxy(h)...)
+		}
+	}
+}
+
+func bypassProxy(fn ProxyFunc, noproxy []string) ProxyFunc {
+	if len(noproxy) == 0 {
+		return fn
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if matchNoProxy(req.URL, noproxy) {
+			return nil, nil
+		}
+		return fn(req)
+	}
+}
+
+func parseProxy(addr string) (*url.URL, error) {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("%s: unsupported proxy scheme", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%s: missing proxy host", addr)
+	}
+	return u, nil
+}
+
+func envNoProxy() string {
+	if str := os.Getenv("NO_PROXY"); str != "" {
+		return str
+	}
+	return os.Getenv("no_proxy")
+}
+
+func splitNoProxy(str string) []string {
+	var list []string
+	for _, str := range strings.Split(str, ",") {
+		str = strings.ToLower(strings.TrimSpace(str))
+		if str != "" {
+			list = append(list, str)
+		}
+	}
+	return list
+}
+
+func matchNoProxy(u *url.URL, noproxy []string) bool {
+	var (
+		host = strings.ToLower(u.Hostname())
+		port = u.Port()
+		ip   = net.ParseIP(host)
+	)
+	for _, str := range noproxy {
+		if str == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(str); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		h, p, err := net.SplitHostPort(str)
+		if err != nil {
+			h, p = str, ""
+		}
+		if p != "" && p != port {
+			continue
+		}
+		h = strings.TrimPrefix(strings.TrimPrefix(h, "*"), ".")
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
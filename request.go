@@ -0,0 +1,160 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	urllib "net/url"
+	"time"
+)
+
+type Response struct {
+	Status   string
+	Code     int
+	Header   http.Header
+	URL      *urllib.URL
+	Length   int64
+	Elapsed  time.Duration
+	Attempts int
+}
+
+func (r *Response) update(res *http.Response, elapsed time.Duration, attempts int) {
+	r.Elapsed = elapsed
+	r.Attempts = attempts
+	if res == nil {
+		return
+	}
+	r.Status = res.Status
+	r.Code = res.StatusCode
+	r.Header = res.Header
+	r.Length = res.ContentLength
+	if res.Request != nil {
+		r.URL = res.Request.URL
+	}
+}
+
+func (r *Response) cached(m *meta) {
+	r.Status = fmt.Sprintf("%d %s", http.StatusOK, http.StatusText(http.StatusOK))
+	r.Code = http.StatusOK
+	r.Header = m.Header.Clone()
+	if r.Header == nil {
+		r.Header = make(http.Header)
+	}
+	if m.Type != "" {
+		r.Header.Set("content-type", m.Type)
+	}
+	r.Length = -1
+	if m.Codec == "" {
+		r.Length = m.Size
+	}
+	if u, err := urllib.Parse(m.URL); err == nil {
+		r.URL = u
+	}
+}
+
+type captureKey struct{}
+
+func captured(req *http.Request) *Response {
+	res, _ := req.Context().Value(captureKey{}).(*Response)
+	return res
+}
+
+type RequestOption func(*call)
+
+func Header(n, v string) RequestOption {
+	return func(c *call) {
+		c.headers.Add(n, v)
+	}
+}
+
+func Credentials(u, p string) RequestOption {
+	return func(c *call) {
+		c.user, c.pass = u, p
+	}
+}
+
+func Bearer(token string) RequestOption {
+	return func(c *call) {
+		c.headers.Set("authorization", "Bearer "+token)
+	}
+}
+
+func Timeout(wait time.Duration) RequestOption {
+	return func(c *call) {
+		c.wait = wait
+	}
+}
+
+func Params(params interface{}) RequestOption {
+	return func(c *call) {
+		c.params = append(c.params, params)
+	}
+}
+
+func Capture(res *Response) RequestOption {
+	return func(c *call) {
+		c.res = res
+	}
+}
+
+func Transform(fn TransformFunc) RequestOption {
+	return func(c *call) {
+		c.transform = append(c.transform, fn)
+	}
+}
+
+func Operation(name string) RequestOption {
+	return func(c *call) {
+		c.operation = name
+	}
+}
+
+func Partial() RequestOption {
+	return func(c *call) {
+		c.partial = true
+	}
+}
+
+type call struct {
+	url       string
+	operation string
+	partial   bool
+	headers   http.Header
+	params    []interface{}
+	user      string
+	pass      string
+	wait      time.Duration
+	transform []TransformFunc
+	res       *Response
+}
+
+func (c *Client) makeCall(url string, opts []RequestOption) (call, error) {
+	cl := call{
+		url:     url,
+		headers: make(http.Header),
+		user:    c.user,
+		pass:    c.pass,
+		wait:    c.wait,
+	}
+	if c.transform != nil {
+		cl.transform = append(cl.transform, c.transform)
+	}
+	for _, fn := range opts {
+		fn(&cl)
+	}
+	for _, p := range cl.params {
+		u, err := BuildURL(cl.url, p)
+		if err != nil {
+			return cl, err
+		}
+		cl.url = u
+	}
+	return cl, nil
+}
+
+func (c call) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.wait <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.wait)
+}
@@ -0,0 +1,177 @@
+package fetch
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type RetryFunc func(int, *http.Response, error) error
+
+type RetryPolicy struct {
+	Retries int
+	Codes   []int
+	Wait    time.Duration
+	MaxWait time.Duration
+	Hook    RetryFunc
+}
+
+func DefaultRetry(retries int) RetryPolicy {
+	return RetryPolicy{
+		Retries: retries,
+		Codes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		Wait:    250 * time.Millisecond,
+		MaxWait: 30 * time.Second,
+	}
+}
+
+func (p RetryPolicy) Transport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return retryTransport{
+		policy: p,
+		next:   rt,
+	}
+}
+
+func (p RetryPolicy) do(req *http.Request, send func(*http.Request) (*http.Response, error)) (*http.Response, int, error) {
+	var attempt int
+	for {
+		attempt++
+		res, err := send(req)
+		if attempt > p.Retries || !p.retryable(req, res, err) {
+			return res, attempt, err
+		}
+		if p.Hook != nil {
+			if err := p.Hook(attempt, res, err); err != nil {
+				discard(res)
+				return nil, attempt, err
+			}
+		}
+		wait := p.delay(attempt, res)
+		discard(res)
+
+		if req, err = rewind(req); err != nil {
+			return nil, attempt, err
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-req.Context().Done():
+			t.Stop()
+			return nil, attempt, req.Context().Err()
+		}
+	}
+}
+
+func (p RetryPolicy) retryable(req *http.Request, res *http.Response, err error) bool {
+	if !isIdempotent(req) || !isRewindable(req) {
+		return false
+	}
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	for _, c := range p.Codes {
+		if res.StatusCode == c {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RetryPolicy) delay(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if wait, ok := retryAfter(res.Header.Get("retry-after")); ok {
+			if p.MaxWait > 0 && wait > p.MaxWait {
+				wait = p.MaxWait
+			}
+			return wait
+		}
+	}
+	wait := p.Wait
+	if wait <= 0 {
+		return 0
+	}
+	for i := 1; i < attempt; i++ {
+		wait *= 2
+		if p.MaxWait > 0 && wait >= p.MaxWait {
+			wait = p.MaxWait
+			break
+		}
+	}
+	half := wait / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+type retryTransport struct {
+	policy RetryPolicy
+	next   http.RoundTripper
+}
+
+func (t retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, _, err := t.policy.do(req, t.next.RoundTrip)
+	return res, err
+}
+
+func retryAfter(str string) (time.Duration, bool) {
+	if str == "" {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(str); err == nil {
+		if n < 0 {
+			return 0, false
+		}
+		return time.Duration(n) * time.Second, true
+	}
+	when, err := http.ParseTime(str)
+	if err != nil {
+		return 0, false
+	}
+	wait := time.Until(when)
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return req.Header.Get("idempotency-key") != ""
+	}
+}
+
+func isRewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.Body = body
+	return r, nil
+}
+
+func discard(res *http.Response) {
+	if res == nil {
+		return
+	}
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4<<10))
+	res.Body.Close()
+}
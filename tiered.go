@@ -0,0 +1,133 @@
+package fetch
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+func WithTieredCache(front, back Cache) Option {
+	return func(c *Client) {
+		c.Cache = TieredCache(front, back)
+	}
+}
+
+type tiered struct {
+	front Cache
+	back  Cache
+}
+
+func TieredCache(front, back Cache) Cache {
+	return &tiered{
+		front: front,
+		back:  back,
+	}
+}
+
+func (t *tiered) Get(req *http.Request, do DoFunc) error {
+	err := t.front.Get(req, do)
+	if err != errMissing && err != errExpired {
+		return err
+	}
+	front, ok := t.front.(*cache)
+	if !ok {
+		return t.back.Get(req, do)
+	}
+	var body []byte
+	err = t.back.Get(req, func(ct string, r io.Reader) error {
+		var buf bytes.Buffer
+		err := do(ct, io.TeeReader(r, &buf))
+		if err == nil {
+			_, err = io.Copy(&buf, r)
+		}
+		body = buf.Bytes()
+		return err
+	})
+	if err == nil {
+		t.promote(front, req, body)
+	}
+	return err
+}
+
+func (t *tiered) Stale(req *http.Request, do DoFunc) error {
+	err := t.front.Stale(req, do)
+	if err != errMissing && err != errExpired {
+		return err
+	}
+	return t.back.Stale(req, do)
+}
+
+func (t *tiered) Do(req *http.Request, res *http.Response, do DoFunc) DoFunc {
+	return t.front.Do(req, res, t.back.Do(req, res, do))
+}
+
+func (t *tiered) Invalidate(req *http.Request, res *http.Response) {
+	t.front.Invalidate(req, res)
+	t.back.Invalidate(req, res)
+}
+
+func (t *tiered) Delete(url string) error {
+	if err := t.front.Delete(url); err != nil {
+		return err
+	}
+	return t.back.Delete(url)
+}
+
+func (t *tiered) Purge() error {
+	if err := t.front.Purge(); err != nil {
+		return err
+	}
+	return t.back.Purge()
+}
+
+func (t *tiered) Stats() CacheStats {
+	var (
+		front = t.front.Stats()
+		back  = t.back.Stats()
+	)
+	return CacheStats{
+		Hits:      front.Hits + back.Hits,
+		Misses:    back.Misses,
+		Stale:     front.Stale + back.Stale,
+		Evictions: front.Evictions + back.Evictions,
+		Entries:   front.Entries + back.Entries,
+		Bytes:     front.Bytes + back.Bytes,
+	}
+}
+
+func (t *tiered) Walk(fn func(CacheEntry) error) error {
+	seen := make(map[string]struct{})
+	err := t.back.Walk(func(e CacheEntry) error {
+		seen[e.URL] = struct{}{}
+		return fn(e)
+	})
+	if err != nil {
+		return err
+	}
+	return t.front.Walk(func(e CacheEntry) error {
+		if _, ok := seen[e.URL]; ok {
+			return nil
+		}
+		return fn(e)
+	})
+}
+
+func (t *tiered) Close() error {
+	err := t.front.Close()
+	if e := t.back.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+func (t *tiered) promote(front *cache, req *http.Request, body []byte) {
+	back, ok := t.back.(*cache)
+	if !ok {
+		return
+	}
+	_, m, err := back.find(req)
+	if err != nil {
+		return
+	}
+	front.fill(req, m, body)
+}